@@ -1,54 +1,98 @@
 package asyncutil
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"runtime"
 	"sync"
+	"time"
 )
 
-// Awaitable 表示一個可以等待的結果
+var (
+	// errorType 是 error 介面的反射類型
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	// contextType 是 context.Context 介面的反射類型
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// Awaitable 表示一個可以等待的結果，內部以 Future 實作
 type Awaitable struct {
-	results []interface{}
-	err     error
-	done    chan struct{}
+	future *Future[[]interface{}]
 }
 
 // NewAwaitable 創建一個新的 Awaitable
 func NewAwaitable(fn interface{}, args ...interface{}) *Awaitable {
-	a := &Awaitable{
-		done: make(chan struct{}),
+	return &Awaitable{
+		future: NewFuture(func() ([]interface{}, error) {
+			return callFunc(nil, fn, args)
+		}),
 	}
+}
 
-	go func() {
-		fnValue := reflect.ValueOf(fn)
-		in := make([]reflect.Value, len(args))
-		for i, arg := range args {
-			in[i] = reflect.ValueOf(arg)
+// callFunc 透過反射呼叫 fn，並將 error 類型的返回值與其他結果分開
+func callFunc(ctx context.Context, fn interface{}, args []interface{}) ([]interface{}, error) {
+	fnType := reflect.TypeOf(fn)
+	out := callRaw(ctx, fn, args)
+
+	var err error
+	results := make([]interface{}, 0, len(out))
+	for i, val := range out {
+		// 檢查是否是 error 類型，值為 nil 的 error 不放入結果
+		if e, ok := val.(error); ok {
+			err = e
+		} else if !fnType.Out(i).Implements(errorType) {
+			results = append(results, val)
 		}
+	}
 
-		out := fnValue.Call(in)
+	return results, err
+}
 
-		a.results = make([]interface{}, 0, len(out))
-		for _, val := range out {
-			// 檢查是否是 error 類型
-			if err, ok := val.Interface().(error); ok {
-				a.err = err
-			} else {
-				a.results = append(a.results, val.Interface())
-			}
-		}
+// callRaw 透過反射呼叫 fn 並返回所有返回值
+// 若 ctx 不為 nil 且 fn 的第一個參數是 context.Context，會自動將 ctx 作為第一個參數傳入
+func callRaw(ctx context.Context, fn interface{}, args []interface{}) []interface{} {
+	fnValue := reflect.ValueOf(fn)
+	if ctx != nil && acceptsContext(fnValue.Type(), args) {
+		args = append([]interface{}{ctx}, args...)
+	}
 
-		close(a.done)
-	}()
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		in[i] = reflect.ValueOf(arg)
+	}
 
-	return a
+	out := fnValue.Call(in)
+
+	// 轉換結果為 interface{} 切片
+	results := make([]interface{}, len(out))
+	for i, val := range out {
+		results[i] = val.Interface()
+	}
+	return results
+}
+
+// acceptsContext 判斷函數的第一個參數是否為 context.Context，且呼叫方尚未自行傳入
+func acceptsContext(fnType reflect.Type, args []interface{}) bool {
+	if fnType.NumIn() == 0 || fnType.In(0) != contextType {
+		return false
+	}
+	if len(args) > 0 {
+		if _, ok := args[0].(context.Context); ok {
+			return false
+		}
+	}
+	return true
 }
 
 // Await 等待結果，返回結果切片和 error
 func (a *Awaitable) Await() ([]interface{}, error) {
-	<-a.done
-	return a.results, a.err
+	return a.future.Await()
+}
+
+// Done 返回一個在異步操作完成時關閉的通道
+func (a *Awaitable) Done() <-chan struct{} {
+	return a.future.Done()
 }
 
 // Async 創建一個異步操作，並返回 Awaitable
@@ -58,49 +102,176 @@ func Async(fn interface{}, args ...interface{}) *Awaitable {
 
 // Task 結構體，包含要執行的函數、其對應的參數和標識符
 type Task struct {
-	ID   string        // 任務的標識符
-	Fn   interface{}   // 要執行的函數
-	Args []interface{} // 函數的參數切片
+	ID        string        // 任務的標識符
+	Fn        interface{}   // 要執行的函數
+	Args      []interface{} // 函數的參數切片
+	DependsOn []string      // 依賴的任務 ID，僅由 RunDAG 使用
+	Retry     *RetryPolicy  // 失敗時的重試策略，nil 表示不重試
+	Priority  int           // 優先順序，數值越大越先執行，僅由 Pool.SubmitTask 使用
+	Weight    int64         // 任務佔用的資源權重，僅由設定了 WithSemaphore 的 Pool 使用，0 表示 1
 }
 
 // TaskResult 結構體，包含每個任務的結果和標識符
 type TaskResult struct {
-	ID      string        // 任務的標識符
-	Results []interface{} // 函數返回的結果
+	ID       string        // 任務的標識符
+	Results  []interface{} // 函數返回的結果，不包括 error 類型的返回值
+	Err      error         // 函數返回的錯誤、panic 或 context 取消的錯誤
+	Panic    *PanicError   // 任務發生 panic 時的值和堆疊追蹤
+	Duration time.Duration // 任務的執行時間，包含重試之間的等待時間
+
+	Attempts      int     // 實際嘗試的次數
+	AttemptErrors []error // 每次失敗嘗試的錯誤
 }
 
 // ParallelProcess 接受一個 Task 切片，平行執行所有的函數並返回結果。
-func ParallelProcess(tasks []Task) []TaskResult {
+// 任務中的 panic 會被捕捉並記錄在 TaskResult 中；可使用 WithFailFast 在第一個失敗時中止其餘任務。
+func ParallelProcess(tasks []Task, opts ...Option) []TaskResult {
+	return ParallelProcessCtx(context.Background(), tasks, opts...)
+}
+
+// ParallelProcessCtx 與 ParallelProcess 相同，但在 ctx 取消時停止等待尚未完成的任務。
+// 第一個參數為 context.Context 的任務函數會收到 ctx，未完成的任務會在 TaskResult.Err 中記錄 ctx 的錯誤。
+func ParallelProcessCtx(ctx context.Context, tasks []Task, opts ...Option) []TaskResult {
 	results := make([]TaskResult, len(tasks))
+	processTasks(ctx, tasks, buildOptions(opts), nil, func(i int, result TaskResult) {
+		results[i] = result
+	})
+	return results
+}
+
+// processTasks 依序啟動所有任務（受 WithConcurrency 限制），每個任務完成時以其索引呼叫 emit。
+// 若 beforeLaunch 不為 nil，會在啟動每個任務前呼叫，可用於限制尚未輸出的任務數量。
+func processTasks(ctx context.Context, tasks []Task, o *options, beforeLaunch func(i int), emit func(i int, result TaskResult)) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// 使用通道限制同時執行的任務數量
+	var limit chan struct{}
+	if o.concurrency > 0 {
+		limit = make(chan struct{}, o.concurrency)
+	}
+
 	var wg sync.WaitGroup
+	observations := o.observeAll(tasks)
+	steps := o.progressSteps(len(tasks))
 
 	for i, task := range tasks {
+		if beforeLaunch != nil {
+			beforeLaunch(i)
+		}
+		if limit != nil {
+			select {
+			case limit <- struct{}{}:
+			case <-ctx.Done():
+				// ctx 已結束，不再啟動剩餘的任務
+				observations[i].finish(context.Cause(ctx))
+				steps[i].Done()
+				emit(i, TaskResult{ID: task.ID, Err: context.Cause(ctx)})
+				continue
+			}
+		}
+
 		wg.Add(1)
 		go func(i int, task Task) {
 			defer wg.Done()
-			fnValue := reflect.ValueOf(task.Fn)
-			in := make([]reflect.Value, len(task.Args))
-			for j, arg := range task.Args {
-				in[j] = reflect.ValueOf(arg)
+			// 在任務自己的 goroutine 中等待限流，避免某個鍵的令牌耗盡時延遲其他鍵的任務；
+			// 等待期間仍佔用並行數量的名額
+			var result TaskResult
+			if err := o.waitRateLimit(ctx, task); err != nil {
+				result = TaskResult{ID: task.ID, Err: err}
+			} else {
+				observations[i].start()
+				result = o.awaitTaskDedup(withProgressStep(ctx, steps[i]), task)
 			}
-
-			out := fnValue.Call(in)
-
-			// 轉換結果為 interface{} 切片
-			result := make([]interface{}, len(out))
-			for j, val := range out {
-				result[j] = val.Interface()
+			observations[i].finish(result.Err)
+			steps[i].Done()
+			if limit != nil {
+				<-limit
 			}
-
-			results[i] = TaskResult{
-				ID:      task.ID,
-				Results: result,
+			if o.failFast && result.Err != nil {
+				cancel(ErrAborted)
 			}
+			emit(i, result)
 		}(i, task)
 	}
 
 	wg.Wait()
-	return results
+}
+
+// awaitTask 執行任務並等待其結果，ctx 結束時立即返回 ctx 的取消原因
+func awaitTask(ctx context.Context, task Task) TaskResult {
+	start := time.Now()
+	future := NewFutureCtx(ctx, func(ctx context.Context) (TaskResult, error) {
+		return runTask(ctx, task), nil
+	})
+
+	result, err := future.Await()
+	if err != nil {
+		return TaskResult{
+			ID:       task.ID,
+			Err:      context.Cause(ctx),
+			Duration: time.Since(start),
+		}
+	}
+	return result
+}
+
+// awaitTaskDedup 執行任務，若設定了 WithDedup，相同 ID 的任務會共用同一次執行的結果。
+// 共用的執行不會因任何一個呼叫方的 ctx 取消而中止，但每個呼叫方在自己的 ctx 結束時會立即停止等待。
+// ID 為空字串的任務不會被合併。
+func (o *options) awaitTaskDedup(ctx context.Context, task Task) TaskResult {
+	if o.dedup == nil || task.ID == "" {
+		return awaitTask(ctx, task)
+	}
+
+	start := time.Now()
+	shared := context.WithoutCancel(ctx)
+	ch := o.dedup.DoChan(task.ID, func() (interface{}, error) {
+		result := awaitTask(shared, task)
+		return result, result.Err
+	})
+	select {
+	case r := <-ch:
+		return r.Val.(TaskResult)
+	case <-ctx.Done():
+		return TaskResult{
+			ID:       task.ID,
+			Err:      context.Cause(ctx),
+			Duration: time.Since(start),
+		}
+	}
+}
+
+// runTask 執行單一任務，依照 Task.Retry 重試，捕捉 panic 並記錄執行時間
+func runTask(ctx context.Context, task Task) TaskResult {
+	start := time.Now()
+	result := TaskResult{ID: task.ID}
+
+	policy := RetryPolicy{MaxAttempts: 1}
+	if task.Retry != nil {
+		policy = *task.Retry
+	}
+
+	result.Attempts, result.AttemptErrors, result.Err = retryLoop(ctx, policy, func(ctx context.Context) error {
+		var err error
+		result.Results, result.Panic, err = runAttempt(ctx, task)
+		return err
+	})
+	result.Duration = time.Since(start)
+	return result
+}
+
+// runAttempt 執行一次任務函數，發生 panic 時返回 *PanicError
+func runAttempt(ctx context.Context, task Task) (results []interface{}, panicErr *PanicError, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr = newPanicError(r)
+			results, err = nil, panicErr
+		}
+	}()
+
+	results, err = callFunc(ctx, task.Fn, task.Args)
+	return results, nil, err
 }
 
 // getDefaultGoroutines 取得預設的線程數
@@ -113,76 +284,69 @@ func getDefaultGoroutines() int {
 }
 
 // ParallelFor 用於平行處理 for 迴圈，支援切片和 map
+// 切片會依索引順序將元素傳給 task，map 則傳入鍵；結果與元素或鍵的順序相同
 func ParallelFor[T any](data interface{}, task func(T) interface{}, numGoroutines ...int) []interface{} {
-	value := reflect.ValueOf(data)
-	kind := value.Kind()
-
-	// 確認是否是支援的類型
-	if kind != reflect.Slice && kind != reflect.Map {
-		panic("ParallelFor: unsupported data type, must be slice or map")
-	}
-
 	// 檢查是否有多個線程數參數
 	if len(numGoroutines) > 1 {
 		panic("ParallelFor: only one goroutine count can be specified")
 	}
 
-	// 取得預設的線程數
-	goroutines := getDefaultGoroutines()
+	var opts []Option
 	if len(numGoroutines) == 1 {
-		goroutines = numGoroutines[0]
+		opts = append(opts, WithConcurrency(numGoroutines[0]))
 	}
+	return ParallelForWith(data, task, opts...)
+}
 
-	length := value.Len()
-	results := make([]interface{}, length)
+// ParallelForWith 與 ParallelFor 相同，但使用 Option 設定線程數（WithConcurrency）和批次大小（WithGrainSize）
+func ParallelForWith[T any](data interface{}, task func(T) interface{}, opts ...Option) []interface{} {
+	value := reflect.ValueOf(data)
+	kind := value.Kind()
 
-	// 決定每個線程處理的數據量
-	chunkSize := (length + goroutines - 1) / goroutines
+	// 確認是否是支援的類型
+	if kind != reflect.Slice && kind != reflect.Map {
+		panic("ParallelFor: unsupported data type, must be slice or map")
+	}
 
-	var wg sync.WaitGroup
+	length := value.Len()
+	results := make([]interface{}, length)
 
 	switch kind {
 	case reflect.Slice:
-		for i := 0; i < goroutines; i++ {
-			wg.Add(1)
-			go func(i int) {
-				defer wg.Done()
-				begin := i * chunkSize
-				finish := begin + chunkSize
-				if finish > length {
-					finish = length
-				}
-				for j := begin; j < finish; j++ {
-					results[j] = task(value.Index(j).Interface().(T))
-				}
-			}(i)
-		}
+		parallelBatches(length, buildOptions(opts), func(begin, end int) {
+			for j := begin; j < end; j++ {
+				results[j] = task(value.Index(j).Interface().(T))
+			}
+		})
 	case reflect.Map:
-		keys := value.MapKeys()
-		for i := 0; i < goroutines; i++ {
-			wg.Add(1)
-			go func(i int) {
-				defer wg.Done()
-				begin := i * chunkSize
-				finish := begin + chunkSize
-				if finish > length {
-					finish = length
-				}
-				for j := begin; j < finish; j++ {
-					k := keys[j].Interface().(T)
-					results[j] = task(k)
-				}
-			}(i)
-		}
+		// map 的鍵只計算一次，並排序以保持結果順序穩定
+		keys := sortedMapKeys(value)
+		parallelBatches(length, buildOptions(opts), func(begin, end int) {
+			for j := begin; j < end; j++ {
+				results[j] = task(keys[j].Interface().(T))
+			}
+		})
 	}
 
-	wg.Wait()
-
 	return results
 }
 
 // ParallelForEach 用於平行處理 for range 迴圈，支援切片和 map
+// 切片會將索引和元素傳給 task，map 則傳入鍵和值；結果與元素或鍵的順序相同
 func ParallelForEach(data interface{}, task interface{}, numGoroutines ...int) []interface{} {
+	if len(numGoroutines) > 1 {
+		panic("ParallelForEach: only one goroutine count can be specified")
+	}
+
+	var opts []Option
+	if len(numGoroutines) == 1 {
+		opts = append(opts, WithConcurrency(numGoroutines[0]))
+	}
+	return ParallelForEachWith(data, task, opts...)
+}
+
+// ParallelForEachWith 與 ParallelForEach 相同，但使用 Option 設定線程數（WithConcurrency）和批次大小（WithGrainSize）
+func ParallelForEachWith(data interface{}, task interface{}, opts ...Option) []interface{} {
 	dataValue := reflect.ValueOf(data)
 	taskValue := reflect.ValueOf(task)
 
@@ -191,58 +355,33 @@ func ParallelForEach(data interface{}, task interface{}, numGoroutines ...int) [
 		panic("ParallelForEach: data must be a slice or map")
 	}
 
-	if len(numGoroutines) > 1 {
-		panic("ParallelForEach: only one goroutine count can be specified")
-	}
-
-	goroutines := getDefaultGoroutines()
-	if len(numGoroutines) == 1 {
-		goroutines = numGoroutines[0]
-	}
-
 	length := dataValue.Len()
 	results := make([]interface{}, length)
 
-	chunkSize := length / goroutines
-	if length%goroutines != 0 {
-		chunkSize++
+	// map 的鍵只計算一次，並排序以保持結果順序穩定
+	var keys []reflect.Value
+	if dataKind == reflect.Map {
+		keys = sortedMapKeys(dataValue)
 	}
 
-	var wg sync.WaitGroup
-	mu := &sync.Mutex{}
-
-	for i := 0; i < goroutines; i++ {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Println("Recovered from panic:", r)
-				}
-			}()
-
-			begin := i * chunkSize
-			finish := begin + chunkSize
-			if finish > length {
-				finish = length
+	parallelBatches(length, buildOptions(opts), func(begin, end int) {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Println("Recovered from panic:", r)
 			}
+		}()
 
-			for j := begin; j < finish; j++ {
-				var result reflect.Value
-				if dataKind == reflect.Slice {
-					result = taskValue.Call([]reflect.Value{reflect.ValueOf(j), dataValue.Index(j)})[0]
-				} else if dataKind == reflect.Map {
-					key := dataValue.MapKeys()[j]
-					result = taskValue.Call([]reflect.Value{key, dataValue.MapIndex(key)})[0]
-				}
-
-				mu.Lock()
-				results[j] = result.Interface()
-				mu.Unlock()
+		for j := begin; j < end; j++ {
+			var result reflect.Value
+			if dataKind == reflect.Slice {
+				result = taskValue.Call([]reflect.Value{reflect.ValueOf(j), dataValue.Index(j)})[0]
+			} else {
+				key := keys[j]
+				result = taskValue.Call([]reflect.Value{key, dataValue.MapIndex(key)})[0]
 			}
-		}(i)
-	}
-	wg.Wait()
+			results[j] = result.Interface()
+		}
+	})
 
 	return results
 }
@@ -0,0 +1,194 @@
+package asyncutil
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed 表示 Batcher 已關閉，不再接受新的項目
+var ErrBatcherClosed = errors.New("asyncutil: batcher is closed")
+
+// Batcher 累積項目，並在數量達到上限或等待時間超過上限時將它們作為一批交給 flush 函數處理，
+// 適合批次寫入資料庫或合併 HTTP 請求。可安全地被多個 goroutine 同時使用。
+type Batcher[T any] struct {
+	mu          sync.Mutex
+	cond        *sync.Cond // 待處理的批次被取走或處理完成時通知
+	size        int
+	maxDelay    time.Duration
+	fn          func(batch []T) error
+	concurrency int
+	clock       Clock
+	errHandler  func(err error)
+	progress    *ProgressTracker
+
+	buf     []T    // 尚未組成批次的項目
+	gen     uint64 // 每次取走 buf 時遞增，用於忽略過期的計時器
+	timer   Timer
+	pending [][]T   // 等待 flush 的批次，依組成順序排列
+	active  int     // 正在處理批次的 goroutine 數量
+	errs    []error // 未設定 WithErrorHandler 時累積的 flush 錯誤
+	closed  bool
+}
+
+// NewBatcher 創建一個 Batcher：累積 size 個項目，或第一個項目加入後經過 maxDelay，就以這些項目呼叫 fn。
+// maxDelay 小於等於 0 時只依數量觸發。可用的選項：
+//   - WithFlushConcurrency(n)：同時執行的 fn 數量上限，預設為 1（依批次組成順序逐一處理）。
+//   - WithErrorHandler(handler)：fn 返回錯誤或發生 panic 時呼叫 handler；未設定時錯誤由 Flush 和 Close 返回。
+//   - WithClock(clock)：替換計算等待時間的時間來源。
+//   - WithProgress(tracker)：每個加入的項目計為一個工作量，在所屬批次處理完成時更新進度。
+func NewBatcher[T any](size int, maxDelay time.Duration, fn func(batch []T) error, opts ...Option) *Batcher[T] {
+	if size < 1 {
+		panic("NewBatcher: size must be at least 1")
+	}
+	if fn == nil {
+		panic("NewBatcher: fn must not be nil")
+	}
+
+	o := buildOptions(opts)
+	concurrency := o.flushConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	b := &Batcher[T]{
+		size:        size,
+		maxDelay:    maxDelay,
+		fn:          fn,
+		concurrency: concurrency,
+		clock:       o.clockOrDefault(),
+		errHandler:  o.errorHandler,
+		progress:    o.progress,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Add 加入一個項目，數量達到上限時組成一批交給 fn 處理。
+// 若等待處理的批次已達到並行上限，Add 會阻塞直到有批次被取走。Batcher 關閉後返回 ErrBatcherClosed。
+func (b *Batcher[T]) Add(item T) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 這個項目會組成新的批次時，等待待處理的批次數量低於上限
+	for !b.closed && len(b.buf)+1 >= b.size && len(b.pending) >= b.concurrency {
+		b.cond.Wait()
+	}
+	if b.closed {
+		return ErrBatcherClosed
+	}
+
+	b.buf = append(b.buf, item)
+	b.progress.AddTotal(1)
+	if len(b.buf) >= b.size {
+		b.pushLocked(b.takeLocked())
+		return nil
+	}
+	if len(b.buf) == 1 && b.maxDelay > 0 {
+		gen := b.gen
+		b.timer = b.clock.AfterFunc(b.maxDelay, func() {
+			b.onTimer(gen)
+		})
+	}
+	return nil
+}
+
+// Len 返回尚未組成批次的項目數量
+func (b *Batcher[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.buf)
+}
+
+// Flush 立即將目前累積的項目組成一批，並等待所有批次處理完成，
+// 返回自上次 Flush 或 Close 以來累積的 flush 錯誤（設定 WithErrorHandler 時為 nil）
+func (b *Batcher[T]) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.drainLocked()
+}
+
+// Close 停止接受新的項目，處理剩餘的項目並等待所有批次處理完成，返回累積的 flush 錯誤。
+// 重複呼叫 Close 是安全的。
+func (b *Batcher[T]) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	b.cond.Broadcast()
+	return b.drainLocked()
+}
+
+// drainLocked 將累積的項目組成一批，等待所有批次處理完成並取走累積的錯誤，呼叫方需持有鎖
+func (b *Batcher[T]) drainLocked() error {
+	if len(b.buf) > 0 {
+		b.pushLocked(b.takeLocked())
+	}
+	for len(b.pending) > 0 || b.active > 0 {
+		b.cond.Wait()
+	}
+
+	err := errors.Join(b.errs...)
+	b.errs = nil
+	return err
+}
+
+// onTimer 在等待時間超過上限時將累積的項目組成一批，gen 已過期時不做任何事
+func (b *Batcher[T]) onTimer(gen uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if gen != b.gen || len(b.buf) == 0 {
+		return
+	}
+	b.pushLocked(b.takeLocked())
+}
+
+// takeLocked 取走累積的項目並停止計時器，呼叫方需持有鎖
+func (b *Batcher[T]) takeLocked() []T {
+	batch := b.buf
+	b.buf = nil
+	b.gen++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return batch
+}
+
+// pushLocked 將批次加入待處理佇列，並在並行數量未達上限時啟動新的處理 goroutine，呼叫方需持有鎖
+func (b *Batcher[T]) pushLocked(batch []T) {
+	b.pending = append(b.pending, batch)
+	if b.active < b.concurrency {
+		b.active++
+		go b.flusher()
+	}
+}
+
+// flusher 持續依序處理待處理的批次，直到佇列為空
+func (b *Batcher[T]) flusher() {
+	b.mu.Lock()
+	for len(b.pending) > 0 {
+		batch := b.pending[0]
+		b.pending[0] = nil
+		b.pending = b.pending[1:]
+		b.cond.Broadcast()
+		b.mu.Unlock()
+
+		_, err := recoverCall(func() (struct{}, error) {
+			return struct{}{}, b.fn(batch)
+		})
+		if err != nil && b.errHandler != nil {
+			b.errHandler(err)
+		}
+		b.progress.Advance(float64(len(batch)))
+
+		b.mu.Lock()
+		if err != nil && b.errHandler == nil {
+			b.errs = append(b.errs, err)
+		}
+	}
+	b.active--
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
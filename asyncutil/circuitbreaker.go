@@ -0,0 +1,285 @@
+package asyncutil
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 表示斷路器處於開啟狀態（或半開狀態的試探名額已滿），函數沒有被執行
+var ErrCircuitOpen = errors.New("asyncutil: circuit breaker is open")
+
+// CircuitState 定義枚舉類型，表示斷路器的狀態
+type CircuitState int
+
+const (
+	StateClosed   CircuitState = iota // 正常狀態，所有呼叫都會執行
+	StateOpen                         // 開啟狀態，所有呼叫都會直接返回 ErrCircuitOpen
+	StateHalfOpen                     // 半開狀態，只允許少量試探呼叫
+)
+
+// String 返回狀態的名稱
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig 定義斷路器的參數，未設定的欄位會使用預設值
+type CircuitBreakerConfig struct {
+	FailureRatio        float64                     // 滾動視窗內失敗比例達到此值時開啟，0 表示不使用
+	MinRequests         int                         // 計算失敗比例所需的最少請求數，預設 10
+	ConsecutiveFailures int                         // 連續失敗次數達到此值時開啟，預設 5（FailureRatio 與其皆為 0 時）
+	Window              time.Duration               // 滾動視窗長度，預設 10 秒
+	Buckets             int                         // 滾動視窗切分的區間數量，預設 10
+	CoolDown            time.Duration               // 開啟後到進入半開狀態的時間，預設 5 秒
+	HalfOpenMaxCalls    int                         // 半開狀態允許的試探呼叫數量，全部成功後關閉，預設 1
+	IsFailure           func(err error) bool        // 判斷錯誤是否算作失敗，nil 表示所有非 nil 錯誤都算失敗
+	OnStateChange       func(from, to CircuitState) // 狀態改變時的回呼函數
+	Clock               Clock                       // 時間來源，nil 表示使用系統時間，可在測試中使用 ManualClock
+}
+
+// windowBucket 保存滾動視窗中一個區間的統計
+type windowBucket struct {
+	epoch     int64 // 區間的編號，用於判斷區間是否過期
+	successes int
+	failures  int
+}
+
+// CircuitBreaker 是一個斷路器，在下游服務持續失敗時暫停呼叫，避免故障擴散
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	cfg         CircuitBreakerConfig
+	clock       Clock
+	state       CircuitState
+	generation  uint64    // 每次狀態改變時遞增，用於忽略舊狀態下開始的呼叫結果
+	openedAt    time.Time // 進入開啟狀態的時間
+	consecutive int       // 連續失敗次數
+	buckets     []windowBucket
+	bucketSize  time.Duration
+
+	halfOpenCalls     int // 半開狀態已放行的試探呼叫數量
+	halfOpenSuccesses int // 半開狀態成功的試探呼叫數量
+}
+
+// NewCircuitBreaker 依照 cfg 創建一個處於關閉狀態的斷路器
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.FailureRatio <= 0 && cfg.ConsecutiveFailures <= 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = 10
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = 5 * time.Second
+	}
+	if cfg.HalfOpenMaxCalls <= 0 {
+		cfg.HalfOpenMaxCalls = 1
+	}
+
+	clock := cfg.Clock
+	if clock == nil {
+		clock = SystemClock()
+	}
+
+	bucketSize := cfg.Window / time.Duration(cfg.Buckets)
+	if bucketSize <= 0 {
+		bucketSize = 1
+	}
+
+	return &CircuitBreaker{
+		cfg:        cfg,
+		clock:      clock,
+		buckets:    make([]windowBucket, cfg.Buckets),
+		bucketSize: bucketSize,
+	}
+}
+
+// State 返回斷路器目前的狀態
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	state, changed := cb.currentStateLocked(cb.clock.Now())
+	cb.mu.Unlock()
+
+	cb.notify(changed)
+	return state
+}
+
+// Execute 若斷路器允許，透過反射同步呼叫 fn 並記錄結果；否則不呼叫 fn，直接返回 ErrCircuitOpen。
+// fn 的返回值處理方式與 Awaitable 相同，fn 中的 panic 會以 *PanicError 返回並算作失敗。
+func (cb *CircuitBreaker) Execute(fn interface{}, args ...interface{}) ([]interface{}, error) {
+	generation, err := cb.before()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := recoverCall(func() ([]interface{}, error) {
+		return callFunc(nil, fn, args)
+	})
+	cb.after(generation, err)
+	return results, err
+}
+
+// ExecuteAsync 與 Execute 相同，但以異步方式執行並返回 Awaitable
+func (cb *CircuitBreaker) ExecuteAsync(fn interface{}, args ...interface{}) *Awaitable {
+	return &Awaitable{
+		future: NewFuture(func() ([]interface{}, error) {
+			return cb.Execute(fn, args...)
+		}),
+	}
+}
+
+// stateChange 表示一次狀態改變，在釋放鎖之後才通知回呼函數
+type stateChange struct {
+	from, to CircuitState
+}
+
+// before 判斷是否允許呼叫，允許時返回目前的狀態世代
+func (cb *CircuitBreaker) before() (uint64, error) {
+	cb.mu.Lock()
+	state, changed := cb.currentStateLocked(cb.clock.Now())
+	generation := cb.generation
+
+	var err error
+	switch state {
+	case StateOpen:
+		err = ErrCircuitOpen
+	case StateHalfOpen:
+		if cb.halfOpenCalls >= cb.cfg.HalfOpenMaxCalls {
+			err = ErrCircuitOpen
+		} else {
+			cb.halfOpenCalls++
+		}
+	}
+	cb.mu.Unlock()
+
+	cb.notify(changed)
+	return generation, err
+}
+
+// after 記錄呼叫結果並依照結果改變狀態
+func (cb *CircuitBreaker) after(generation uint64, err error) {
+	failed := err != nil
+	if failed && cb.cfg.IsFailure != nil {
+		failed = cb.cfg.IsFailure(err)
+	}
+
+	now := cb.clock.Now()
+	cb.mu.Lock()
+	var changed []stateChange
+
+	// 忽略在舊狀態下開始的呼叫
+	if generation == cb.generation {
+		switch cb.state {
+		case StateClosed:
+			cb.recordLocked(now, failed)
+			if cb.shouldTripLocked(now) {
+				changed = cb.setStateLocked(StateOpen, now)
+			}
+		case StateHalfOpen:
+			if failed {
+				changed = cb.setStateLocked(StateOpen, now)
+			} else {
+				cb.halfOpenSuccesses++
+				if cb.halfOpenSuccesses >= cb.cfg.HalfOpenMaxCalls {
+					changed = cb.setStateLocked(StateClosed, now)
+				}
+			}
+		}
+	}
+	cb.mu.Unlock()
+
+	cb.notify(changed)
+}
+
+// currentStateLocked 返回目前的狀態，開啟狀態超過冷卻時間時轉為半開狀態，呼叫方需持有鎖
+func (cb *CircuitBreaker) currentStateLocked(now time.Time) (CircuitState, []stateChange) {
+	var changed []stateChange
+	if cb.state == StateOpen && now.Sub(cb.openedAt) >= cb.cfg.CoolDown {
+		changed = cb.setStateLocked(StateHalfOpen, now)
+	}
+	return cb.state, changed
+}
+
+// setStateLocked 改變狀態並重置統計，呼叫方需持有鎖
+func (cb *CircuitBreaker) setStateLocked(to CircuitState, now time.Time) []stateChange {
+	from := cb.state
+	cb.state = to
+	cb.generation++
+	cb.consecutive = 0
+	cb.halfOpenCalls = 0
+	cb.halfOpenSuccesses = 0
+	if to == StateOpen {
+		cb.openedAt = now
+	}
+	if to == StateClosed {
+		for i := range cb.buckets {
+			cb.buckets[i] = windowBucket{}
+		}
+	}
+	return []stateChange{{from: from, to: to}}
+}
+
+// recordLocked 將呼叫結果記錄到目前的區間，呼叫方需持有鎖
+func (cb *CircuitBreaker) recordLocked(now time.Time, failed bool) {
+	epoch := now.UnixNano() / int64(cb.bucketSize)
+	b := &cb.buckets[epoch%int64(len(cb.buckets))]
+	if b.epoch != epoch {
+		*b = windowBucket{epoch: epoch}
+	}
+
+	if failed {
+		b.failures++
+		cb.consecutive++
+	} else {
+		b.successes++
+		cb.consecutive = 0
+	}
+}
+
+// shouldTripLocked 判斷是否達到開啟的門檻，呼叫方需持有鎖
+func (cb *CircuitBreaker) shouldTripLocked(now time.Time) bool {
+	if cb.cfg.ConsecutiveFailures > 0 && cb.consecutive >= cb.cfg.ConsecutiveFailures {
+		return true
+	}
+	if cb.cfg.FailureRatio <= 0 {
+		return false
+	}
+
+	// 只統計仍在滾動視窗內的區間
+	epoch := now.UnixNano() / int64(cb.bucketSize)
+	oldest := epoch - int64(len(cb.buckets)) + 1
+	var successes, failures int
+	for _, b := range cb.buckets {
+		if b.epoch >= oldest && b.epoch <= epoch {
+			successes += b.successes
+			failures += b.failures
+		}
+	}
+
+	total := successes + failures
+	return total >= cb.cfg.MinRequests && float64(failures)/float64(total) >= cb.cfg.FailureRatio
+}
+
+// notify 在釋放鎖之後呼叫狀態改變的回呼函數
+func (cb *CircuitBreaker) notify(changed []stateChange) {
+	if cb.cfg.OnStateChange == nil {
+		return
+	}
+	for _, c := range changed {
+		cb.cfg.OnStateChange(c.from, c.to)
+	}
+}
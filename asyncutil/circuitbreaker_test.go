@@ -0,0 +1,218 @@
+package asyncutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	errDownstream = errors.New("downstream failed")
+	errIgnored    = errors.New("ignored")
+)
+
+func succeed() error { return nil }
+func fail() error    { return errDownstream }
+
+func TestCircuitBreakerTrip(t *testing.T) {
+	// 步驟："s" 成功、"f" 失敗、"i" 不算失敗的錯誤、"+" 時間前進一秒
+	tests := []struct {
+		name  string
+		cfg   CircuitBreakerConfig
+		steps string
+		want  []CircuitState // 每個步驟之後的狀態
+	}{
+		{
+			name:  "consecutive failures",
+			cfg:   CircuitBreakerConfig{ConsecutiveFailures: 3},
+			steps: "ffsfff",
+			want:  []CircuitState{StateClosed, StateClosed, StateClosed, StateClosed, StateClosed, StateOpen},
+		},
+		{
+			name:  "default threshold is five consecutive failures",
+			cfg:   CircuitBreakerConfig{},
+			steps: "fffff",
+			want:  []CircuitState{StateClosed, StateClosed, StateClosed, StateClosed, StateOpen},
+		},
+		{
+			name:  "IsFailure excludes errors",
+			cfg:   CircuitBreakerConfig{ConsecutiveFailures: 2, IsFailure: func(err error) bool { return !errors.Is(err, errIgnored) }},
+			steps: "iiif",
+			want:  []CircuitState{StateClosed, StateClosed, StateClosed, StateClosed},
+		},
+		{
+			name:  "failure ratio waits for MinRequests",
+			cfg:   CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4, Window: 4 * time.Second, Buckets: 4},
+			steps: "sfsf",
+			want:  []CircuitState{StateClosed, StateClosed, StateClosed, StateOpen},
+		},
+		{
+			name:  "failure ratio below threshold",
+			cfg:   CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4, Window: 4 * time.Second, Buckets: 4},
+			steps: "ssfsfs",
+			want:  []CircuitState{StateClosed, StateClosed, StateClosed, StateClosed, StateClosed, StateClosed},
+		},
+		{
+			name: "failures outside the rolling window are forgotten",
+			cfg:  CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4, Window: 4 * time.Second, Buckets: 4},
+			// 三次失敗在視窗外後，s 之後視窗內只有 1 個請求；若仍計入舊區間，s 時就會開啟
+			steps: "fff+++++sfff",
+			want: []CircuitState{
+				StateClosed, StateClosed, StateClosed,
+				StateClosed, StateClosed, StateClosed, StateClosed, StateClosed,
+				StateClosed, StateClosed, StateClosed, StateOpen,
+			},
+		},
+		{
+			name:  "buckets still inside the window are counted",
+			cfg:   CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4, Window: 4 * time.Second, Buckets: 4},
+			steps: "ff++ss",
+			want:  []CircuitState{StateClosed, StateClosed, StateClosed, StateClosed, StateClosed, StateOpen},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewManualClock(time.Unix(1000, 0))
+			tt.cfg.Clock = clock
+			cb := NewCircuitBreaker(tt.cfg)
+
+			for i, step := range tt.steps {
+				switch step {
+				case 's':
+					cb.Execute(succeed)
+				case 'f':
+					cb.Execute(fail)
+				case 'i':
+					cb.Execute(func() error { return errIgnored })
+				case '+':
+					clock.Advance(time.Second)
+				}
+				if got := cb.State(); got != tt.want[i] {
+					t.Fatalf("after step %d (%c): state = %v, want %v", i, step, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	var transitions [][2]CircuitState
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		CoolDown:            5 * time.Second,
+		HalfOpenMaxCalls:    2,
+		Clock:               clock,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, [2]CircuitState{from, to})
+		},
+	})
+
+	cb.Execute(fail)
+	if _, err := cb.Execute(succeed); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Execute while open = %v, want ErrCircuitOpen", err)
+	}
+
+	// 冷卻時間結束前仍為開啟狀態
+	clock.Advance(4 * time.Second)
+	if s := cb.State(); s != StateOpen {
+		t.Fatalf("state before CoolDown = %v, want open", s)
+	}
+	clock.Advance(time.Second)
+	if s := cb.State(); s != StateHalfOpen {
+		t.Fatalf("state after CoolDown = %v, want half-open", s)
+	}
+
+	// 半開狀態的試探呼叫失敗時重新開啟
+	cb.Execute(fail)
+	if s := cb.State(); s != StateOpen {
+		t.Fatalf("state after half-open failure = %v, want open", s)
+	}
+
+	// HalfOpenMaxCalls 個試探呼叫同時進行時，其餘呼叫被拒絕；全部成功後關閉
+	clock.Advance(5 * time.Second)
+	release := make(chan struct{})
+	probes := []*Awaitable{
+		cb.ExecuteAsync(func() { <-release }),
+		cb.ExecuteAsync(func() { <-release }),
+	}
+	waitFor(t, func() bool {
+		cb.mu.Lock()
+		defer cb.mu.Unlock()
+		return cb.halfOpenCalls == 2
+	})
+	if _, err := cb.Execute(succeed); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Execute beyond HalfOpenMaxCalls = %v, want ErrCircuitOpen", err)
+	}
+	if s := cb.State(); s != StateHalfOpen {
+		t.Errorf("state with probes in flight = %v, want half-open", s)
+	}
+	close(release)
+	for _, p := range probes {
+		if _, err := p.Await(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := cb.State(); s != StateClosed {
+		t.Fatalf("state after successful probes = %v, want closed", s)
+	}
+
+	want := [][2]CircuitState{
+		{StateClosed, StateOpen},
+		{StateOpen, StateHalfOpen},
+		{StateHalfOpen, StateOpen},
+		{StateOpen, StateHalfOpen},
+		{StateHalfOpen, StateClosed},
+	}
+	if !reflect.DeepEqual(transitions, want) {
+		t.Errorf("OnStateChange calls = %v, want %v", transitions, want)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleGeneration(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	cb := NewCircuitBreaker(CircuitBreakerConfig{ConsecutiveFailures: 1, CoolDown: time.Second, Clock: clock})
+
+	// 在關閉狀態開始的慢速呼叫
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := cb.ExecuteAsync(func() { close(started); <-release })
+	<-started
+
+	cb.Execute(fail)
+	clock.Advance(time.Second)
+	if s := cb.State(); s != StateHalfOpen {
+		t.Fatalf("state = %v, want half-open", s)
+	}
+
+	// 舊世代的成功不能讓半開狀態關閉
+	close(release)
+	slow.Await()
+	if s := cb.State(); s != StateHalfOpen {
+		t.Errorf("state after stale success = %v, want half-open", s)
+	}
+
+	// 舊世代的失敗也不能讓關閉狀態開啟
+	cb.Execute(succeed)
+	if s := cb.State(); s != StateClosed {
+		t.Fatalf("state after probe = %v, want closed", s)
+	}
+	cb.after(0, errDownstream)
+	if s := cb.State(); s != StateClosed {
+		t.Errorf("state after stale failure = %v, want closed", s)
+	}
+}
+
+// waitFor 輪詢直到 cond 為 true，逾時則使測試失敗
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
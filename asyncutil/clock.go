@@ -0,0 +1,114 @@
+package asyncutil
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 抽象了時間來源，可替換為 ManualClock 以便在測試中不必真正等待
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer 表示由 Clock.AfterFunc 創建的計時器
+type Timer interface {
+	// Stop 停止計時器，若計時器已觸發或已停止則返回 false
+	Stop() bool
+}
+
+// systemClock 使用 time 套件的 Clock 實作
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// SystemClock 返回使用系統時間的 Clock，這是所有接受 Clock 的函數的預設值
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// ManualClock 是一個只有在呼叫 Advance 時才會前進的 Clock，用於測試
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+// manualTimer 是 ManualClock 創建的計時器
+type manualTimer struct {
+	clock *ManualClock
+	when  time.Time
+	f     func()
+}
+
+// NewManualClock 創建一個從 start 開始的 ManualClock
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now 返回 ManualClock 目前的時間
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc 創建一個在時間前進 d 之後呼叫 f 的計時器，f 會在呼叫 Advance 的 goroutine 中執行
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &manualTimer{clock: c, when: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance 將時間前進 d，並依照到期時間的順序觸發期間內所有到期的計時器
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		// 依到期時間排序，到期時間相同時保持創建順序
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].when.Before(c.timers[j].when)
+		})
+		if len(c.timers) == 0 || c.timers[0].when.After(target) {
+			break
+		}
+
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+
+		// 觸發計時器時釋放鎖，讓回呼函數可以再次使用 ManualClock
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// Stop 停止計時器，若計時器已觸發或已停止則返回 false
+func (t *manualTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
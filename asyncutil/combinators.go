@@ -0,0 +1,99 @@
+package asyncutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Awaiter 表示一個可以等待結果的異步操作，Awaitable 和 Future 都實作了此介面
+type Awaiter[T any] interface {
+	Await() (T, error)
+	Done() <-chan struct{}
+}
+
+// Settled 表示一個異步操作的最終結果
+type Settled[T any] struct {
+	Value T     // 操作的結果
+	Err   error // 操作的錯誤
+}
+
+// AggregateError 包含多個異步操作的錯誤，由 AwaitAny 在所有操作都失敗時返回
+type AggregateError struct {
+	Errors []error // 依照傳入順序排列的錯誤
+}
+
+// Error 實作 error 介面
+func (e *AggregateError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("asyncutil: all %d operations failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap 返回所有錯誤，支援 errors.Is 和 errors.As
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// completionOrder 返回一個通道，依照完成順序輸出各個 Awaiter 的索引
+func completionOrder[T any](awaiters []Awaiter[T]) <-chan int {
+	ch := make(chan int, len(awaiters))
+	for i, a := range awaiters {
+		go func(i int, a Awaiter[T]) {
+			<-a.Done()
+			ch <- i
+		}(i, a)
+	}
+	return ch
+}
+
+// AwaitAll 等待所有操作成功並依照傳入順序返回結果，任何一個操作失敗時立即返回該錯誤
+func AwaitAll[T any](awaiters ...Awaiter[T]) ([]T, error) {
+	values := make([]T, len(awaiters))
+	order := completionOrder(awaiters)
+	for range awaiters {
+		i := <-order
+		value, err := awaiters[i].Await()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// AwaitAllSettled 等待所有操作完成，並依照傳入順序返回每個操作的結果和錯誤
+func AwaitAllSettled[T any](awaiters ...Awaiter[T]) []Settled[T] {
+	settled := make([]Settled[T], len(awaiters))
+	for i, a := range awaiters {
+		settled[i].Value, settled[i].Err = a.Await()
+	}
+	return settled
+}
+
+// AwaitAny 返回第一個成功完成的操作結果，若所有操作都失敗則返回 *AggregateError
+func AwaitAny[T any](awaiters ...Awaiter[T]) (T, error) {
+	errs := make([]error, len(awaiters))
+	order := completionOrder(awaiters)
+	for range awaiters {
+		i := <-order
+		value, err := awaiters[i].Await()
+		if err == nil {
+			return value, nil
+		}
+		errs[i] = err
+	}
+
+	var zero T
+	return zero, &AggregateError{Errors: errs}
+}
+
+// Race 返回第一個完成的操作的結果和錯誤，無論成功或失敗
+func Race[T any](awaiters ...Awaiter[T]) (T, error) {
+	if len(awaiters) == 0 {
+		var zero T
+		return zero, ErrNoAwaiters
+	}
+	return awaiters[<-completionOrder(awaiters)].Await()
+}
@@ -0,0 +1,28 @@
+package asyncutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFuture2WithCombinators(t *testing.T) {
+	a := NewFuture2(func() (string, int, error) { return "a", 1, nil })
+	b := NewFuture2(func() (string, int, error) { return "b", 2, nil })
+
+	pairs, err := AwaitAll[Pair[string, int]](a.Future(), b.Future())
+	if err != nil {
+		t.Fatalf("AwaitAll: %v", err)
+	}
+	if pairs[0] != (Pair[string, int]{"a", 1}) || pairs[1] != (Pair[string, int]{"b", 2}) {
+		t.Errorf("AwaitAll = %v", pairs)
+	}
+
+	boom := errors.New("boom")
+	failed := NewFuture2(func() (string, int, error) { return "", 0, boom })
+	if _, err := Race[Pair[string, int]](failed.Future()); !errors.Is(err, boom) {
+		t.Errorf("Race error = %v, want %v", err, boom)
+	}
+	if _, _, err := failed.Await(); !errors.Is(err, boom) {
+		t.Errorf("Await error = %v, want %v", err, boom)
+	}
+}
@@ -0,0 +1,99 @@
+package asyncutil
+
+import (
+	"context"
+	"time"
+)
+
+// NewFutureCtx 在新的 goroutine 中執行 fn 並傳入 ctx。
+// 若 ctx 在 fn 完成前被取消或逾時，Future 會立即以 ctx 的錯誤完成，不再等待 fn。
+func NewFutureCtx[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) *Future[T] {
+	f := newPendingFuture[T]()
+
+	// ctx 已經結束時不再啟動 fn
+	if err := ctx.Err(); err != nil {
+		var zero T
+		f.complete(zero, err)
+		return f
+	}
+
+	go func() {
+		value, err := recoverCall(func() (T, error) {
+			return fn(ctx)
+		})
+		f.complete(value, err)
+	}()
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				var zero T
+				f.complete(zero, ctx.Err())
+			case <-f.done:
+			}
+		}()
+	}
+
+	return f
+}
+
+// AwaitCtx 等待結果，若 ctx 先被取消則返回 ctx 的錯誤
+func (f *Future[T]) AwaitCtx(ctx context.Context) (T, error) {
+	// 結果已就緒時優先返回結果
+	select {
+	case <-f.done:
+		return f.value, f.err
+	default:
+	}
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// AwaitTimeout 最多等待 timeout，逾時則返回 context.DeadlineExceeded
+func (f *Future[T]) AwaitTimeout(timeout time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return f.AwaitCtx(ctx)
+}
+
+// AsyncCtx 創建一個可取消的異步操作，並返回 Awaitable。
+// 若 fn 的第一個參數是 context.Context，ctx 會自動作為第一個參數傳入；
+// 若 ctx 在 fn 完成前被取消或逾時，Await 會立即返回 ctx 的錯誤。
+// 若 ctx 帶有 ContextWithObserver 設定的 Observer，fn 的執行情況會回報給它。
+func AsyncCtx(ctx context.Context, fn interface{}, args ...interface{}) *Awaitable {
+	ob := observe(ObserverFromContext(ctx), "")
+	future := NewFutureCtx(ctx, func(ctx context.Context) ([]interface{}, error) {
+		ob.start()
+		results, err := recoverCall(func() ([]interface{}, error) {
+			return callFunc(ctx, fn, args)
+		})
+		ob.finish(err)
+		return results, err
+	})
+	if ob != nil {
+		// ctx 在 fn 開始前結束時，fn 不會被執行，需在此回報任務結束
+		go func() {
+			<-future.Done()
+			_, err := future.Await()
+			ob.abandon(err)
+		}()
+	}
+	return &Awaitable{future: future}
+}
+
+// AwaitCtx 等待結果，若 ctx 先被取消則返回 ctx 的錯誤
+func (a *Awaitable) AwaitCtx(ctx context.Context) ([]interface{}, error) {
+	return a.future.AwaitCtx(ctx)
+}
+
+// AwaitTimeout 最多等待 timeout，逾時則返回 context.DeadlineExceeded
+func (a *Awaitable) AwaitTimeout(timeout time.Duration) ([]interface{}, error) {
+	return a.future.AwaitTimeout(timeout)
+}
@@ -0,0 +1,205 @@
+package asyncutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule 是解析後的 cron 表達式
+type CronSchedule struct {
+	second, minute, hour, dom, month, dow uint64 // 每個欄位允許的值，以位元表示
+	domRestricted, dowRestricted          bool   // 日期和星期欄位是否不是 *
+}
+
+// cronField 描述 cron 表達式中一個欄位的範圍和名稱
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronSecond = cronField{name: "second", min: 0, max: 59}
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	// 星期欄位允許 7 代表星期日
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+// cronDescriptors 是預先定義的 cron 表達式
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron 解析標準的 5 欄位（分 時 日 月 星期）或 6 欄位（秒 分 時 日 月 星期）cron 表達式。
+// 支援 *、?、數值、範圍（a-b）、間隔（*/n、a-b/n、a/n）、逗號分隔的列表、月份和星期的英文縮寫，
+// 以及 @yearly、@monthly、@weekly、@daily、@hourly 等預設表達式。
+// 與標準 cron 相同，日期和星期欄位都有限制時，只要符合其中之一即會執行。
+func ParseCron(spec string) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("asyncutil: invalid cron expression %q: expected 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	s := &CronSchedule{}
+	var err error
+	targets := []struct {
+		bits  *uint64
+		field cronField
+	}{
+		{&s.second, cronSecond},
+		{&s.minute, cronMinute},
+		{&s.hour, cronHour},
+		{&s.dom, cronDom},
+		{&s.month, cronMonth},
+		{&s.dow, cronDow},
+	}
+	for i, t := range targets {
+		if *t.bits, err = parseCronField(fields[i], t.field); err != nil {
+			return nil, fmt.Errorf("asyncutil: invalid cron expression %q: %w", spec, err)
+		}
+	}
+
+	// 7 與 0 都代表星期日
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = fields[3] != "*" && fields[3] != "?"
+	s.dowRestricted = fields[5] != "*" && fields[5] != "?"
+	return s, nil
+}
+
+// parseCronField 解析一個欄位，返回允許值的位元集合
+func parseCronField(expr string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", field.name, part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			low, high = field.min, field.max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], field); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(bounds[1], field); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if low, err = parseCronValue(rangePart, field); err != nil {
+				return 0, err
+			}
+			high = low
+			// a/n 表示從 a 到最大值，每 n 個
+			if strings.Contains(part, "/") {
+				high = field.max
+			}
+		}
+
+		if low > high {
+			return 0, fmt.Errorf("invalid range in %s field: %q", field.name, part)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseCronValue 解析欄位中的單一數值或名稱
+func parseCronValue(s string, field cronField) (int, error) {
+	if v, ok := field.names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < field.min || v > field.max {
+		return 0, fmt.Errorf("invalid value in %s field: %q", field.name, s)
+	}
+	return v, nil
+}
+
+// Next 返回 t 之後（不包含 t）第一個符合表達式的時間，使用 t 的時區計算；五年內都沒有符合的時間時返回零值
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			// 以絕對時間前進到下一個整點：夏令時間開始當天，time.Date 會將不存在的時間正規化回較早的時間而無法前進
+			t = t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// advance 返回 next；若 next 因夏令時間的正規化而沒有晚於 t（例如午夜不存在的時區），改為以絕對時間前進一小時
+func advance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Hour)
+}
+
+// dayMatches 判斷日期是否符合日期和星期欄位
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
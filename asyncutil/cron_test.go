@@ -0,0 +1,117 @@
+package asyncutil
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "@unknown"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) returned no error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	start := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC) // 星期四
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2026, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon", time.Date(2026, 1, 19, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, 1, 18, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"30 * * * * *", time.Date(2026, 1, 15, 10, 30, 30, 0, time.UTC)},
+		{"@daily", time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)},
+		// 同時限制日期和星期時，符合任一個即可
+		{"0 0 20 * fri", time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.spec, err)
+		}
+		if got := s.Next(start); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextDSTStart(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+
+	// 2026-03-08 02:00 EST 跳到 03:00 EDT
+	s, err := ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := nextWithin(t, s, time.Date(2026, 3, 7, 10, 0, 0, 0, loc))
+	if want := time.Date(2026, 3, 8, 9, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+
+	// 不存在的 02:30 被略過
+	s, err = ParseCron("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nextWithin(t, s, time.Date(2026, 3, 8, 0, 0, 0, 0, loc))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestCronNextDSTEnd(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+
+	// 2026-11-01 02:00 EDT 回到 01:00 EST
+	s, err := ParseCron("0 3 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := nextWithin(t, s, time.Date(2026, 10, 31, 12, 0, 0, 0, loc))
+	if want := time.Date(2026, 11, 1, 3, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+
+	s, err = ParseCron("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 11, 1, 0, 30, 0, 0, loc)
+	for i := 0; i < 4; i++ {
+		next := nextWithin(t, s, from)
+		if next.Sub(from) > time.Hour {
+			t.Fatalf("Next(%v) = %v skips more than an hour", from, next)
+		}
+		from = next
+	}
+}
+
+// nextWithin 呼叫 s.Next，若未在時間內返回則使測試失敗，避免無限迴圈使測試卡住
+func nextWithin(t *testing.T, s *CronSchedule, from time.Time) time.Time {
+	t.Helper()
+	done := make(chan time.Time, 1)
+	go func() { done <- s.Next(from) }()
+	select {
+	case next := <-done:
+		return next
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Next(%v) did not return", from)
+		return time.Time{}
+	}
+}
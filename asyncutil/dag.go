@@ -0,0 +1,221 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrDuplicateTaskID 表示 RunDAG 收到了重複的 Task.ID
+	ErrDuplicateTaskID = errors.New("asyncutil: duplicate task ID")
+	// ErrUnknownDependency 表示 Task.DependsOn 引用了不存在的 Task.ID
+	ErrUnknownDependency = errors.New("asyncutil: unknown task dependency")
+	// ErrCycle 表示任務之間的依賴關係形成了循環
+	ErrCycle = errors.New("asyncutil: dependency cycle detected")
+	// ErrSkipped 表示任務因為依賴的任務失敗而未執行，可透過 errors.Is 判斷
+	ErrSkipped = errors.New("asyncutil: task skipped")
+)
+
+// upstreamType 是 Upstream 的反射類型
+var upstreamType = reflect.TypeOf(Upstream(nil))
+
+// Upstream 保存任務所依賴的上游任務結果，以 Task.ID 為鍵。
+// 若任務函數的最後一個參數類型是 Upstream，RunDAG 會自動將上游結果作為最後一個參數傳入。
+type Upstream map[string]TaskResult
+
+// SkipError 表示任務因依賴的任務失敗或被跳過而未執行
+type SkipError struct {
+	Dependency string // 失敗的依賴任務 ID
+	Cause      error  // 依賴任務的錯誤
+}
+
+// Error 實作 error 介面
+func (e *SkipError) Error() string {
+	return fmt.Sprintf("asyncutil: task skipped because dependency %q failed: %v", e.Dependency, e.Cause)
+}
+
+// Is 讓 errors.Is(err, ErrSkipped) 返回 true
+func (e *SkipError) Is(target error) bool {
+	return target == ErrSkipped
+}
+
+// Unwrap 返回依賴任務的錯誤
+func (e *SkipError) Unwrap() error {
+	return e.Cause
+}
+
+// RunDAG 依照 Task.DependsOn 的依賴關係執行任務：沒有依賴關係的任務會平行執行（可用 WithConcurrency 限制數量），
+// 任務只會在所有依賴的任務成功後才開始，依賴任務失敗時以 *SkipError 跳過。
+// 重複的 ID、不存在的依賴或循環依賴會在執行任何任務前以錯誤返回。返回的結果與 tasks 的順序相同。
+func RunDAG(ctx context.Context, tasks []Task, opts ...Option) ([]TaskResult, error) {
+	index, err := validateDAG(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	o := buildOptions(opts)
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	n := len(tasks)
+	results := make([]TaskResult, n)
+	pending := make([]int, n)      // 每個任務尚未完成的依賴數量
+	dependents := make([][]int, n) // 依賴每個任務的任務索引
+	var ready []int
+	for i, task := range tasks {
+		pending[i] = len(task.DependsOn)
+		for _, dep := range task.DependsOn {
+			dependents[index[dep]] = append(dependents[index[dep]], i)
+		}
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	done := make(chan int, n)
+	running, finished := 0, 0
+	observations := o.observeAll(tasks)
+	steps := o.progressSteps(n)
+
+	// complete 記錄任務完成，並將所有依賴已完成的下游任務加入就緒佇列
+	complete := func(i int) {
+		finished++
+		for _, d := range dependents[i] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	for finished < n {
+		for len(ready) > 0 && (o.concurrency <= 0 || running < o.concurrency) {
+			i := ready[0]
+			ready = ready[1:]
+			task := tasks[i]
+
+			if skip := failedDependency(task, index, results); skip != nil {
+				results[i] = TaskResult{ID: task.ID, Err: skip}
+				observations[i].finish(skip)
+				steps[i].Done()
+				complete(i)
+				continue
+			}
+
+			running++
+			upstream := make(Upstream, len(task.DependsOn))
+			for _, dep := range task.DependsOn {
+				upstream[dep] = results[index[dep]]
+			}
+			go func(i int, task Task) {
+				task.Args = withUpstream(task.Fn, task.Args, upstream)
+				if err := o.waitRateLimit(ctx, task); err != nil {
+					results[i] = TaskResult{ID: task.ID, Err: err}
+				} else {
+					observations[i].start()
+					results[i] = awaitTask(withProgressStep(ctx, steps[i]), task)
+				}
+				observations[i].finish(results[i].Err)
+				steps[i].Done()
+				if o.failFast && results[i].Err != nil {
+					cancel(ErrAborted)
+				}
+				done <- i
+			}(i, task)
+		}
+
+		if finished == n {
+			break
+		}
+		i := <-done
+		running--
+		complete(i)
+	}
+
+	return results, nil
+}
+
+// failedDependency 返回任務第一個失敗的依賴所對應的 SkipError，全部成功時返回 nil
+func failedDependency(task Task, index map[string]int, results []TaskResult) error {
+	for _, dep := range task.DependsOn {
+		if err := results[index[dep]].Err; err != nil {
+			return &SkipError{Dependency: dep, Cause: err}
+		}
+	}
+	return nil
+}
+
+// withUpstream 若函數的最後一個參數類型是 Upstream 且呼叫方尚未傳入，將上游結果附加到參數後面
+func withUpstream(fn interface{}, args []interface{}, upstream Upstream) []interface{} {
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil || fnType.Kind() != reflect.Func || fnType.NumIn() == 0 || len(args) >= fnType.NumIn() {
+		return args
+	}
+	if fnType.In(fnType.NumIn()-1) != upstreamType {
+		return args
+	}
+
+	withArgs := make([]interface{}, len(args), len(args)+1)
+	copy(withArgs, args)
+	return append(withArgs, upstream)
+}
+
+// validateDAG 檢查任務 ID 是否重複、依賴是否存在以及是否有循環依賴，並返回 ID 到索引的對應
+func validateDAG(tasks []Task) (map[string]int, error) {
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		if _, exists := index[task.ID]; exists {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateTaskID, task.ID)
+		}
+		index[task.ID] = i
+	}
+	for _, task := range tasks {
+		for _, dep := range task.DependsOn {
+			if _, exists := index[dep]; !exists {
+				return nil, fmt.Errorf("%w: task %q depends on %q", ErrUnknownDependency, task.ID, dep)
+			}
+		}
+	}
+
+	// 使用深度優先搜尋尋找循環，0 表示未訪問，1 表示訪問中，2 表示已完成
+	state := make([]int, len(tasks))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		state[i] = 1
+		path = append(path, tasks[i].ID)
+		for _, dep := range tasks[i].DependsOn {
+			j := index[dep]
+			switch state[j] {
+			case 1:
+				start := 0
+				for k, id := range path {
+					if id == dep {
+						start = k
+					}
+				}
+				cycle := append(append([]string{}, path[start:]...), dep)
+				return fmt.Errorf("%w: %s", ErrCycle, strings.Join(cycle, " -> "))
+			case 0:
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = 2
+		return nil
+	}
+	for i := range tasks {
+		if state[i] == 0 {
+			if err := visit(i); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return index, nil
+}
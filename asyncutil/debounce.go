@@ -0,0 +1,246 @@
+package asyncutil
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer 將短時間內連續的呼叫合併為一次，由 Debounce 創建，可安全地被多個 goroutine 同時使用
+type Debouncer struct {
+	mu       sync.Mutex
+	clock    Clock
+	fn       func()
+	wait     time.Duration
+	maxWait  time.Duration
+	leading  bool
+	trailing bool
+
+	inBurst  bool   // 是否處於一連串呼叫之中
+	pending  bool   // 是否有尚未執行的尾端呼叫
+	timer    Timer  // 最後一次呼叫後 wait 到期的計時器
+	maxTimer Timer  // 第一次未執行的呼叫後 maxWait 到期的計時器
+	gen      uint64 // 每次重設計時器時遞增，用於忽略過期的計時器回呼
+	maxGen   uint64 // 每次設定 maxTimer 時遞增，用於忽略過期的 maxTimer 回呼
+}
+
+// Debounce 返回一個 Debouncer：每次 Call 都會重新計時，直到 wait 時間內沒有新的呼叫才執行 fn。
+// 預設只在尾端執行，可使用 WithEdges 設定前端/尾端執行、WithMaxWait 設定最長等待時間、WithClock 替換時間來源。
+func Debounce(wait time.Duration, fn func(), opts ...Option) *Debouncer {
+	o := buildOptions(opts)
+	d := &Debouncer{
+		clock:    o.clockOrDefault(),
+		fn:       fn,
+		wait:     wait,
+		maxWait:  o.maxWait,
+		leading:  false,
+		trailing: true,
+	}
+	if o.edgesSet {
+		d.leading, d.trailing = o.leading, o.trailing
+	}
+	return d
+}
+
+// Call 觸發一次呼叫
+func (d *Debouncer) Call() {
+	d.mu.Lock()
+	fire := false
+	if !d.inBurst {
+		d.inBurst = true
+		fire = d.leading
+		d.pending = !d.leading && d.trailing
+	} else {
+		d.pending = d.trailing
+	}
+
+	d.gen++
+	gen := d.gen
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = d.clock.AfterFunc(d.wait, func() { d.onWait(gen) })
+	if d.maxWait > 0 && d.pending && d.maxTimer == nil {
+		d.maxGen++
+		maxGen := d.maxGen
+		d.maxTimer = d.clock.AfterFunc(d.maxWait, func() { d.onMaxWait(maxGen) })
+	}
+	d.mu.Unlock()
+
+	if fire {
+		d.fn()
+	}
+}
+
+// onWait 在最後一次呼叫後經過 wait 時執行尾端呼叫並結束這一連串呼叫
+func (d *Debouncer) onWait(gen uint64) {
+	d.mu.Lock()
+	if gen != d.gen {
+		d.mu.Unlock()
+		return
+	}
+	fire := d.resetLocked()
+	d.mu.Unlock()
+
+	if fire {
+		d.fn()
+	}
+}
+
+// onMaxWait 在等待超過 maxWait 時立即執行尾端呼叫，但不結束這一連串呼叫
+func (d *Debouncer) onMaxWait(maxGen uint64) {
+	d.mu.Lock()
+	if maxGen != d.maxGen || d.maxTimer == nil {
+		d.mu.Unlock()
+		return
+	}
+	fire := d.pending
+	d.pending = false
+	d.maxTimer = nil
+	d.mu.Unlock()
+
+	if fire {
+		d.fn()
+	}
+}
+
+// Flush 立即執行尚未執行的尾端呼叫（如果有），並結束這一連串呼叫
+func (d *Debouncer) Flush() {
+	d.mu.Lock()
+	fire := d.resetLocked()
+	d.mu.Unlock()
+
+	if fire {
+		d.fn()
+	}
+}
+
+// Cancel 取消尚未執行的尾端呼叫，並結束這一連串呼叫
+func (d *Debouncer) Cancel() {
+	d.mu.Lock()
+	d.resetLocked()
+	d.mu.Unlock()
+}
+
+// resetLocked 停止所有計時器並清除狀態，返回是否有尚未執行的尾端呼叫，呼叫方需持有鎖
+func (d *Debouncer) resetLocked() bool {
+	pending := d.pending
+	d.gen++
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.maxTimer != nil {
+		d.maxTimer.Stop()
+		d.maxTimer = nil
+		d.maxGen++
+	}
+	d.inBurst = false
+	d.pending = false
+	return pending
+}
+
+// Throttler 限制函數在每個時間間隔內最多執行一次，由 Throttle 創建，可安全地被多個 goroutine 同時使用
+type Throttler struct {
+	mu       sync.Mutex
+	clock    Clock
+	fn       func()
+	interval time.Duration
+	leading  bool
+	trailing bool
+
+	active  bool   // 是否處於一個時間間隔之中
+	pending bool   // 是否有尚未執行的尾端呼叫
+	timer   Timer  // 目前時間間隔結束的計時器
+	gen     uint64 // 每次重設計時器時遞增，用於忽略過期的計時器回呼
+}
+
+// Throttle 返回一個 Throttler：每個 interval 內最多執行一次 fn。
+// 預設在時間間隔的前端和尾端都會執行，可使用 WithEdges 調整，WithClock 替換時間來源。
+func Throttle(interval time.Duration, fn func(), opts ...Option) *Throttler {
+	o := buildOptions(opts)
+	t := &Throttler{
+		clock:    o.clockOrDefault(),
+		fn:       fn,
+		interval: interval,
+		leading:  true,
+		trailing: true,
+	}
+	if o.edgesSet {
+		t.leading, t.trailing = o.leading, o.trailing
+	}
+	return t
+}
+
+// Call 觸發一次呼叫
+func (t *Throttler) Call() {
+	t.mu.Lock()
+	fire := false
+	if !t.active {
+		t.active = true
+		t.startLocked()
+		fire = t.leading
+		t.pending = !t.leading && t.trailing
+	} else {
+		t.pending = t.trailing
+	}
+	t.mu.Unlock()
+
+	if fire {
+		t.fn()
+	}
+}
+
+// startLocked 開始一個新的時間間隔，呼叫方需持有鎖
+func (t *Throttler) startLocked() {
+	t.gen++
+	gen := t.gen
+	t.timer = t.clock.AfterFunc(t.interval, func() { t.onInterval(gen) })
+}
+
+// onInterval 在時間間隔結束時執行尾端呼叫；若有執行，則開始新的時間間隔
+func (t *Throttler) onInterval(gen uint64) {
+	t.mu.Lock()
+	if gen != t.gen {
+		t.mu.Unlock()
+		return
+	}
+	fire := t.pending
+	t.pending = false
+	if fire {
+		t.startLocked()
+	} else {
+		t.active = false
+		t.timer = nil
+	}
+	t.mu.Unlock()
+
+	if fire {
+		t.fn()
+	}
+}
+
+// Flush 立即執行尚未執行的尾端呼叫（如果有），時間間隔不受影響
+func (t *Throttler) Flush() {
+	t.mu.Lock()
+	fire := t.pending
+	t.pending = false
+	t.mu.Unlock()
+
+	if fire {
+		t.fn()
+	}
+}
+
+// Cancel 取消尚未執行的尾端呼叫並結束目前的時間間隔
+func (t *Throttler) Cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.gen++
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.active = false
+	t.pending = false
+}
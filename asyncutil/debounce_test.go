@@ -0,0 +1,133 @@
+package asyncutil
+
+import (
+	"testing"
+	"time"
+)
+
+// counter 記錄函數被呼叫的次數，ManualClock 在呼叫 Advance 的 goroutine 中觸發計時器，因此不需要同步
+type counter int
+
+func (c *counter) inc() { *c++ }
+
+func (c *counter) expect(t *testing.T, step string, want int) {
+	t.Helper()
+	if int(*c) != want {
+		t.Errorf("%s: calls = %d, want %d", step, int(*c), want)
+	}
+}
+
+func TestDebounceTrailing(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var calls counter
+	d := Debounce(100*time.Millisecond, calls.inc, WithClock(clock))
+
+	d.Call()
+	clock.Advance(60 * time.Millisecond)
+	d.Call() // 重新計時
+	clock.Advance(60 * time.Millisecond)
+	calls.expect(t, "before wait elapses", 0)
+
+	clock.Advance(40 * time.Millisecond)
+	calls.expect(t, "after wait", 1)
+
+	clock.Advance(time.Second)
+	calls.expect(t, "idle", 1)
+}
+
+func TestDebounceLeading(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var calls counter
+	d := Debounce(100*time.Millisecond, calls.inc, WithClock(clock), WithEdges(true, false))
+
+	d.Call()
+	calls.expect(t, "first call", 1)
+	d.Call()
+	clock.Advance(50 * time.Millisecond)
+	d.Call()
+	clock.Advance(time.Second)
+	calls.expect(t, "after burst", 1)
+
+	d.Call() // 新的一連串呼叫
+	calls.expect(t, "next burst", 2)
+}
+
+func TestDebounceMaxWait(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var calls counter
+	d := Debounce(100*time.Millisecond, calls.inc, WithClock(clock), WithMaxWait(250*time.Millisecond))
+
+	// 每 50ms 呼叫一次，wait 永遠不會到期，但每 250ms 至少執行一次
+	for i := 0; i < 10; i++ {
+		d.Call()
+		clock.Advance(50 * time.Millisecond)
+	}
+	calls.expect(t, "during burst", 2) // 250ms 和 500ms
+
+	// 最後一次呼叫（450ms）已由 500ms 的執行涵蓋，不會再執行尾端呼叫
+	clock.Advance(100 * time.Millisecond)
+	calls.expect(t, "after burst", 2)
+
+	d.Call()
+	clock.Advance(100 * time.Millisecond)
+	calls.expect(t, "next burst", 3)
+}
+
+func TestDebounceFlushAndCancel(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var calls counter
+	d := Debounce(100*time.Millisecond, calls.inc, WithClock(clock))
+
+	d.Call()
+	d.Flush()
+	calls.expect(t, "Flush", 1)
+	d.Flush()
+	calls.expect(t, "second Flush", 1)
+
+	d.Call()
+	d.Cancel()
+	clock.Advance(time.Second)
+	calls.expect(t, "Cancel", 1)
+}
+
+func TestThrottle(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var calls counter
+	th := Throttle(100*time.Millisecond, calls.inc, WithClock(clock))
+
+	th.Call()
+	calls.expect(t, "leading", 1)
+	th.Call()
+	th.Call()
+	clock.Advance(50 * time.Millisecond)
+	calls.expect(t, "within interval", 1)
+
+	clock.Advance(50 * time.Millisecond)
+	calls.expect(t, "trailing", 2)
+
+	// 尾端執行開始了新的時間間隔，期間的呼叫在下一個間隔結束時執行
+	th.Call()
+	calls.expect(t, "call after trailing", 2)
+	clock.Advance(100 * time.Millisecond)
+	calls.expect(t, "second trailing", 3)
+
+	clock.Advance(100 * time.Millisecond) // 沒有新呼叫，時間間隔結束
+	th.Call()
+	calls.expect(t, "new interval leading", 4)
+}
+
+func TestThrottleTrailingOnly(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var calls counter
+	th := Throttle(100*time.Millisecond, calls.inc, WithClock(clock), WithEdges(false, true))
+
+	th.Call()
+	calls.expect(t, "no leading", 0)
+	clock.Advance(100 * time.Millisecond)
+	calls.expect(t, "trailing", 1)
+
+	th.Call()
+	th.Cancel()
+	clock.Advance(time.Second)
+	calls.expect(t, "Cancel", 1)
+}
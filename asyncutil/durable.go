@@ -0,0 +1,505 @@
+package asyncutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/HazelnutParadise/Go-Utils/jsonutil"
+)
+
+var (
+	// ErrQueueClosed 表示 DurableQueue 已關閉
+	ErrQueueClosed = errors.New("asyncutil: durable queue is closed")
+	// ErrJobNotInFlight 表示指定 ID 的工作不是正在處理中的工作
+	ErrJobNotInFlight = errors.New("asyncutil: job is not in flight")
+	// ErrJobNotDead 表示指定 ID 的工作不在死信佇列中
+	ErrJobNotDead = errors.New("asyncutil: job is not a dead letter")
+)
+
+// durableRecord 是寫入檔案的一筆操作記錄
+type durableRecord struct {
+	Op       string          `json:"op"` // put、ack、nack、dead、requeue 或 seq（壓縮時記錄已使用的最大 ID）
+	ID       uint64          `json:"id"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Attempts int             `json:"attempts,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// 工作的狀態
+const (
+	jobPending = iota
+	jobInFlight
+	jobDead
+)
+
+// DurableJob 是 DurableQueue 中的一個工作
+type DurableJob struct {
+	ID        uint64          // 工作的標識符，依加入順序遞增
+	Payload   json.RawMessage // 工作內容的 JSON
+	Attempts  int             // 已失敗的次數
+	LastError string          // 最後一次失敗的錯誤訊息
+
+	state int
+}
+
+// Decode 將工作內容解析到 v
+func (j *DurableJob) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// DurableQueue 是以本地檔案保存的持久化工作佇列。所有操作以 JSON Lines 格式追加到檔案中，
+// 程式重新啟動後以 OpenDurableQueue 重新開啟即可從中斷的地方繼續：尚未確認的工作會重新投遞。
+type DurableQueue struct {
+	mu          sync.Mutex
+	path        string
+	file        *os.File
+	maxAttempts int
+	jobs        map[uint64]*DurableJob
+	ready       []uint64      // 等待投遞的工作 ID，依先後順序排列
+	inFlight    int           // 正在處理中的工作數量
+	nextID      uint64        // 下一個工作的 ID
+	changed     chan struct{} // 有工作可投遞、工作處理完成或佇列關閉時關閉並重建
+	closed      bool
+}
+
+// OpenDurableQueue 開啟或創建位於 path 的持久化佇列，並重播檔案中的記錄以恢復狀態。
+// 寫入中斷而不完整的最後一行會被忽略。開啟時會壓縮檔案，只保留尚未完成的工作。
+// 可使用 WithMaxAttempts(n) 設定工作失敗幾次後移到死信佇列，預設為 3。
+func OpenDurableQueue(path string, opts ...Option) (*DurableQueue, error) {
+	o := buildOptions(opts)
+	maxAttempts := o.maxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 3
+	}
+
+	q := &DurableQueue{
+		path:        path,
+		maxAttempts: maxAttempts,
+		jobs:        make(map[uint64]*DurableJob),
+		nextID:      1,
+		changed:     make(chan struct{}),
+	}
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+	if err := q.compactLocked(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// replay 讀取檔案中的所有記錄並恢復工作的狀態，處理中的工作會恢復為等待投遞
+func (q *DurableQueue) replay() error {
+	file, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = jsonutil.ReadJSONLines(file, func(line json.RawMessage) error {
+		var rec durableRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		q.apply(rec)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("asyncutil: replay %s: %w", q.path, err)
+	}
+
+	ids := make([]uint64, 0, len(q.jobs))
+	for id, job := range q.jobs {
+		if job.state != jobDead {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	q.ready = ids
+	return nil
+}
+
+// apply 將一筆記錄套用到記憶體中的狀態
+func (q *DurableQueue) apply(rec durableRecord) {
+	if rec.ID >= q.nextID {
+		q.nextID = rec.ID + 1
+	}
+
+	job := q.jobs[rec.ID]
+	if rec.Op == "put" {
+		q.jobs[rec.ID] = &DurableJob{ID: rec.ID, Payload: rec.Payload, Attempts: rec.Attempts, LastError: rec.Error}
+		return
+	}
+	if job == nil {
+		return
+	}
+
+	switch rec.Op {
+	case "ack":
+		delete(q.jobs, rec.ID)
+	case "nack":
+		job.Attempts++
+		job.LastError = rec.Error
+	case "dead":
+		job.state = jobDead
+	case "requeue":
+		job.state = jobPending
+		job.Attempts = 0
+	}
+}
+
+// writeLocked 將記錄追加到檔案並同步到磁碟，呼叫方需持有鎖
+func (q *DurableQueue) writeLocked(rec durableRecord) error {
+	if err := jsonutil.AppendJSONLine(q.file, rec); err != nil {
+		return err
+	}
+	return q.file.Sync()
+}
+
+// notifyLocked 喚醒所有等待佇列狀態改變的呼叫方，呼叫方需持有鎖
+func (q *DurableQueue) notifyLocked() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Enqueue 將 payload 編碼為 JSON 並加入佇列，寫入磁碟後返回工作的 ID
+func (q *DurableQueue) Enqueue(payload interface{}) (uint64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return 0, ErrQueueClosed
+	}
+	id := q.nextID
+	if err := q.writeLocked(durableRecord{Op: "put", ID: id, Payload: data}); err != nil {
+		return 0, err
+	}
+
+	q.nextID++
+	q.jobs[id] = &DurableJob{ID: id, Payload: data}
+	q.ready = append(q.ready, id)
+	q.notifyLocked()
+	return id, nil
+}
+
+// Dequeue 取出下一個工作，佇列為空時阻塞直到有新工作、ctx 被取消或佇列關閉。
+// 取出的工作必須以 Ack 或 Nack 回報結果，否則在重新開啟佇列後會被再次投遞。
+func (q *DurableQueue) Dequeue(ctx context.Context) (*DurableJob, error) {
+	return q.take(ctx, false)
+}
+
+// take 取出下一個工作。stopWhenIdle 為 true 時，若沒有等待投遞和處理中的工作則返回 nil
+func (q *DurableQueue) take(ctx context.Context, stopWhenIdle bool) (*DurableJob, error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		if len(q.ready) > 0 {
+			job := q.jobs[q.ready[0]]
+			q.ready = q.ready[1:]
+			job.state = jobInFlight
+			q.inFlight++
+			copied := *job
+			q.mu.Unlock()
+			return &copied, nil
+		}
+		if stopWhenIdle && q.inFlight == 0 {
+			q.mu.Unlock()
+			return nil, nil
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Ack 確認工作已處理完成並將其從佇列中永久移除，也可用於丟棄死信佇列中的工作
+func (q *DurableQueue) Ack(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	job := q.jobs[id]
+	if job == nil || job.state == jobPending {
+		return ErrJobNotInFlight
+	}
+	if err := q.writeLocked(durableRecord{Op: "ack", ID: id}); err != nil {
+		return err
+	}
+
+	if job.state == jobInFlight {
+		q.inFlight--
+	}
+	delete(q.jobs, id)
+	q.notifyLocked()
+	return nil
+}
+
+// Nack 回報工作處理失敗。失敗次數未達上限時，工作會回到佇列尾端等待重新投遞，否則移到死信佇列
+func (q *DurableQueue) Nack(id uint64, cause error) error {
+	_, err := q.nack(id, cause)
+	return err
+}
+
+// nack 實作 Nack，並返回工作是否回到佇列等待重新投遞。
+// 結果在持有鎖時決定，因此不受其他 goroutine 隨後取走該工作的影響。
+func (q *DurableQueue) nack(id uint64, cause error) (requeued bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false, ErrQueueClosed
+	}
+	job := q.jobs[id]
+	if job == nil || job.state != jobInFlight {
+		return false, ErrJobNotInFlight
+	}
+
+	rec := durableRecord{Op: "nack", ID: id}
+	if cause != nil {
+		rec.Error = cause.Error()
+	}
+	if err := q.writeLocked(rec); err != nil {
+		return false, err
+	}
+	job.Attempts++
+	job.LastError = rec.Error
+	q.inFlight--
+
+	if job.Attempts >= q.maxAttempts {
+		if err := q.writeLocked(durableRecord{Op: "dead", ID: id}); err != nil {
+			// 記錄失敗時工作仍留在佇列中，重新開啟後會再次投遞
+			job.state = jobPending
+			q.ready = append(q.ready, id)
+			q.notifyLocked()
+			return true, err
+		}
+		job.state = jobDead
+	} else {
+		job.state = jobPending
+		q.ready = append(q.ready, id)
+		requeued = true
+	}
+	q.notifyLocked()
+	return requeued, nil
+}
+
+// release 將處理中的工作放回佇列前端，不計入失敗次數
+func (q *DurableQueue) release(id uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job := q.jobs[id]
+	if job == nil || job.state != jobInFlight {
+		return
+	}
+	job.state = jobPending
+	q.inFlight--
+	q.ready = append([]uint64{id}, q.ready...)
+	q.notifyLocked()
+}
+
+// DeadLetters 返回失敗次數達到上限的工作，依 ID 排序
+func (q *DurableQueue) DeadLetters() []DurableJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var dead []DurableJob
+	for _, job := range q.jobs {
+		if job.state == jobDead {
+			dead = append(dead, *job)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool { return dead[i].ID < dead[j].ID })
+	return dead
+}
+
+// Requeue 將死信佇列中的工作重設失敗次數後放回佇列尾端
+func (q *DurableQueue) Requeue(id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	job := q.jobs[id]
+	if job == nil || job.state != jobDead {
+		return ErrJobNotDead
+	}
+	if err := q.writeLocked(durableRecord{Op: "requeue", ID: id}); err != nil {
+		return err
+	}
+
+	job.state = jobPending
+	job.Attempts = 0
+	q.ready = append(q.ready, id)
+	q.notifyLocked()
+	return nil
+}
+
+// Len 返回等待投遞的工作數量，不包括處理中的工作和死信
+func (q *DurableQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready)
+}
+
+// Process 以 WithWorkers(n) 設定的 worker 數量（預設為 1）平行地取出工作並呼叫 fn，
+// fn 返回 nil 時確認工作，返回錯誤或發生 panic 時回報失敗。
+// 所有工作都處理完成（佇列為空且沒有處理中的工作）時返回 nil；ctx 被取消時返回 ctx 的錯誤。
+// 設定 WithProgress 時，開始時等待投遞的工作會加入總工作量，每個工作在確認或移到死信佇列時計為完成。
+func (q *DurableQueue) Process(ctx context.Context, fn func(ctx context.Context, job *DurableJob) error, opts ...Option) error {
+	o := buildOptions(opts)
+	workers := o.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	o.progress.AddTotal(float64(q.Len()))
+
+	scope, ctx := NewScope(ctx)
+	for i := 0; i < workers; i++ {
+		scope.Go(func(ctx context.Context) error {
+			for {
+				job, err := q.take(ctx, true)
+				if err != nil || job == nil {
+					return err
+				}
+
+				// 工作可能被重試，因此總工作量已在開始時計算，不另外增加
+				var step *ProgressStep
+				if o.progress != nil {
+					step = &ProgressStep{tracker: o.progress}
+				}
+				_, err = recoverCall(func() (struct{}, error) {
+					return struct{}{}, fn(withProgressStep(ctx, step), job)
+				})
+				if err != nil && ctx.Err() != nil {
+					// 因取消而中斷的工作不算失敗
+					step.reset()
+					q.release(job.ID)
+					return ctx.Err()
+				}
+				requeued := false
+				if err == nil {
+					err = q.Ack(job.ID)
+				} else {
+					requeued, err = q.nack(job.ID, err)
+				}
+				if err != nil {
+					return err
+				}
+
+				if requeued {
+					step.reset()
+				} else {
+					step.Done()
+				}
+			}
+		})
+	}
+	return scope.Wait()
+}
+
+// Compact 重寫檔案，只保留尚未完成的工作和死信，避免檔案無限制地增長
+func (q *DurableQueue) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	return q.compactLocked()
+}
+
+// compactLocked 將目前的狀態寫入暫存檔案後取代原檔案，並重新開啟以便追加，呼叫方需持有鎖
+func (q *DurableQueue) compactLocked() error {
+	ids := make([]uint64, 0, len(q.jobs))
+	for id := range q.jobs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	tmpPath := q.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	err = func() error {
+		// 保留已使用的最大 ID，確保所有工作都已確認後重新開啟時 ID 仍然遞增
+		if q.nextID > 1 {
+			if err := jsonutil.AppendJSONLine(tmp, durableRecord{Op: "seq", ID: q.nextID - 1}); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			job := q.jobs[id]
+			rec := durableRecord{Op: "put", ID: id, Payload: job.Payload, Attempts: job.Attempts, Error: job.LastError}
+			if err := jsonutil.AppendJSONLine(tmp, rec); err != nil {
+				return err
+			}
+			if job.state == jobDead {
+				if err := jsonutil.AppendJSONLine(tmp, durableRecord{Op: "dead", ID: id}); err != nil {
+					return err
+				}
+			}
+		}
+		return tmp.Sync()
+	}()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, q.path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	file, err := os.OpenFile(q.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if q.file != nil {
+		q.file.Close()
+	}
+	q.file = file
+	return nil
+}
+
+// Close 關閉佇列和檔案，阻塞中的 Dequeue 會返回 ErrQueueClosed。
+// 處理中的工作尚未確認，重新開啟佇列後會再次投遞。
+func (q *DurableQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	q.notifyLocked()
+	return q.file.Close()
+}
@@ -0,0 +1,205 @@
+package asyncutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestQueue(t *testing.T, path string, opts ...Option) *DurableQueue {
+	t.Helper()
+	q, err := OpenDurableQueue(path, opts...)
+	if err != nil {
+		t.Fatalf("OpenDurableQueue: %v", err)
+	}
+	return q
+}
+
+func TestDurableQueueReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	ctx := context.Background()
+
+	q := openTestQueue(t, path, WithMaxAttempts(1))
+	for _, s := range []string{"acked", "in-flight", "dead", "pending"} {
+		if _, err := q.Enqueue(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	acked, _ := q.Dequeue(ctx)
+	q.Ack(acked.ID)
+	q.Dequeue(ctx) // 不確認，模擬處理中程式中止
+	dead, _ := q.Dequeue(ctx)
+	q.Nack(dead.ID, errors.New("boom"))
+	q.Close()
+
+	q = openTestQueue(t, path, WithMaxAttempts(1))
+	defer q.Close()
+
+	var got []string
+	for q.Len() > 0 {
+		job, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s string
+		if err := job.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+	if len(got) != 2 || got[0] != "in-flight" || got[1] != "pending" {
+		t.Errorf("replayed jobs = %v, want [in-flight pending]", got)
+	}
+
+	letters := q.DeadLetters()
+	if len(letters) != 1 || letters[0].ID != dead.ID || letters[0].Attempts != 1 || letters[0].LastError != "boom" {
+		t.Fatalf("DeadLetters() = %+v", letters)
+	}
+	if err := q.Requeue(dead.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := q.Len(); n != 1 {
+		t.Errorf("Len after Requeue = %d, want 1", n)
+	}
+}
+
+func TestDurableQueueTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	q := openTestQueue(t, path)
+	q.Enqueue("complete")
+	q.Close()
+
+	// 模擬寫入到一半時程式中止
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"put","id":2,"payl`)
+	f.Close()
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	if n := q.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+	if id, _ := q.Enqueue("next"); id != 2 {
+		t.Errorf("Enqueue id = %d, want 2", id)
+	}
+}
+
+func TestDurableQueueCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	ctx := context.Background()
+
+	q := openTestQueue(t, path)
+	for i := 0; i < 100; i++ {
+		q.Enqueue(i)
+	}
+	for i := 0; i < 99; i++ {
+		job, _ := q.Dequeue(ctx)
+		q.Ack(job.ID)
+	}
+	before, _ := os.Stat(path)
+	if err := q.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("file size after Compact = %d, want less than %d", after.Size(), before.Size())
+	}
+
+	// 壓縮後的檔案仍可追加
+	q.Enqueue(100)
+	q.Close()
+
+	data, _ := os.ReadFile(path)
+	if lines := bytes.Count(data, []byte("\n")); lines != 3 {
+		t.Errorf("compacted file has %d lines, want 3 (seq and two puts):\n%s", lines, data)
+	}
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	var got []int
+	for q.Len() > 0 {
+		job, _ := q.Dequeue(ctx)
+		var n int
+		job.Decode(&n)
+		got = append(got, n)
+	}
+	if len(got) != 2 || got[0] != 99 || got[1] != 100 {
+		t.Errorf("jobs after Compact = %v, want [99 100]", got)
+	}
+}
+
+func TestDurableQueueIDsSurviveCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	ctx := context.Background()
+
+	q := openTestQueue(t, path)
+	for i := 0; i < 3; i++ {
+		if _, err := q.Enqueue(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		job, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Ack(job.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	// 重新開啟兩次：第一次開啟時的壓縮也必須保留最大 ID
+	for i := 0; i < 2; i++ {
+		q = openTestQueue(t, path)
+		id, err := q.Enqueue("next")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := uint64(4 + i); id != want {
+			t.Errorf("reopen %d: Enqueue id = %d, want %d", i, id, want)
+		}
+		job, _ := q.Dequeue(ctx)
+		q.Ack(job.ID)
+		q.Close()
+	}
+}
+
+func TestDurableQueueProcessProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	q := openTestQueue(t, path, WithMaxAttempts(2))
+	defer q.Close()
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		q.Enqueue(i)
+	}
+
+	tracker := NewProgressTracker(0, nil)
+	err := q.Process(context.Background(), func(ctx context.Context, job *DurableJob) error {
+		if job.ID%2 == 0 {
+			return errors.New("even")
+		}
+		return nil
+	}, WithWorkers(8), WithProgress(tracker))
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	p := tracker.Snapshot()
+	if p.Completed != n || p.Total != n {
+		t.Errorf("progress = %v/%v, want %d/%d", p.Completed, p.Total, n, n)
+	}
+	if dead := q.DeadLetters(); len(dead) != n/2 {
+		t.Errorf("len(DeadLetters()) = %d, want %d", len(dead), n/2)
+	}
+}
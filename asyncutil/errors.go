@@ -0,0 +1,51 @@
+package asyncutil
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+var (
+	// ErrAborted 表示任務因為其他任務失敗（例如啟用了 WithFailFast）而被中止
+	ErrAborted = errors.New("asyncutil: task aborted because another task failed")
+	// ErrNoAwaiters 表示 Race 沒有收到任何可等待的操作
+	ErrNoAwaiters = errors.New("asyncutil: no awaiters given")
+)
+
+// PanicError 表示任務執行期間發生的 panic，包含 panic 的值和堆疊追蹤
+type PanicError struct {
+	Value interface{} // recover() 取得的值
+	Stack []byte      // 發生 panic 時的堆疊追蹤
+}
+
+// newPanicError 以 recover() 的值創建 PanicError，並記錄當前的堆疊追蹤
+func newPanicError(value interface{}) *PanicError {
+	return &PanicError{
+		Value: value,
+		Stack: debug.Stack(),
+	}
+}
+
+// Error 實作 error 介面
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("asyncutil: panic: %v", e.Value)
+}
+
+// Unwrap 若 panic 的值本身是 error，則返回該 error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverCall 呼叫 fn，並將 fn 中發生的 panic 轉換為 *PanicError
+func recoverCall[T any](fn func() (T, error)) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,238 @@
+package asyncutil
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy 定義枚舉類型，指定非同步訂閱者的佇列已滿時如何處理新事件
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // 阻塞發布者直到佇列出現空位
+	OverflowDropNewest                       // 捨棄新的事件
+	OverflowDropOldest                       // 捨棄佇列中最舊的事件
+)
+
+// Topic 是帶有型別的事件主題，發布到主題的值必須是 T 類型
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic 創建一個名稱為 name 的主題，名稱以 "." 分隔層級，例如 "orders.created"
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name 返回主題的名稱
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// Bus 是行程內的發布/訂閱事件匯流排，可安全地被多個 goroutine 同時使用
+type Bus struct {
+	mu      sync.RWMutex
+	subs    map[uint64]*Subscription
+	nextID  uint64
+	onPanic func(topic string, err *PanicError)
+}
+
+// NewBus 創建一個事件匯流排，可使用 WithPanicHandler 接收訂閱者處理事件時發生的 panic。
+// 訂閱者的 panic 一律會被捕捉，不影響其他訂閱者；未設定 WithPanicHandler 時 panic 會被靜默捨棄。
+func NewBus(opts ...Option) *Bus {
+	return &Bus{
+		subs:    make(map[uint64]*Subscription),
+		onPanic: buildOptions(opts).panicHandler,
+	}
+}
+
+// Subscription 表示一個訂閱，可透過 Unsubscribe 取消
+type Subscription struct {
+	bus     *Bus
+	id      uint64
+	pattern []string
+	accepts func(value interface{}) bool          // 判斷事件的類型是否符合訂閱的類型
+	deliver func(topic string, value interface{}) // 處理事件
+	dropped uint64
+
+	// 以下欄位僅用於非同步訂閱
+	async  bool
+	size   int
+	policy OverflowPolicy
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []busEvent
+	closed bool
+}
+
+// busEvent 是非同步訂閱佇列中的一個事件
+type busEvent struct {
+	topic string
+	value interface{}
+}
+
+// Subscribe 訂閱符合 pattern 的主題中類型為 T 的事件。pattern 以 "." 分隔層級，
+// "*" 符合一個層級，"#" 符合零個或多個層級，例如 "orders.*" 或 "orders.#"。
+// 預設在發布者的 goroutine 中同步處理事件，使用 WithAsyncDelivery 可改為透過獨立的緩衝佇列非同步處理。
+// handler 中的 panic 會被捕捉，不會影響發布者或其他訂閱者。
+func Subscribe[T any](bus *Bus, pattern string, handler func(topic string, value T), opts ...Option) *Subscription {
+	o := buildOptions(opts)
+	sub := &Subscription{
+		bus:     bus,
+		pattern: strings.Split(pattern, "."),
+		accepts: func(value interface{}) bool {
+			_, ok := value.(T)
+			return ok
+		},
+		deliver: func(topic string, value interface{}) {
+			handler(topic, value.(T))
+		},
+	}
+
+	if o.asyncBuffer > 0 {
+		sub.async = true
+		sub.size = o.asyncBuffer
+		sub.policy = o.overflowPolicy
+		sub.cond = sync.NewCond(&sub.mu)
+		go sub.loop()
+	}
+
+	bus.mu.Lock()
+	bus.nextID++
+	sub.id = bus.nextID
+	bus.subs[sub.id] = sub
+	bus.mu.Unlock()
+
+	return sub
+}
+
+// Publish 將 value 發布到 topic，返回接收此事件的訂閱者數量（非同步訂閱者中被捨棄的事件不計入）
+func Publish[T any](bus *Bus, topic Topic[T], value T) int {
+	segments := strings.Split(topic.name, ".")
+
+	bus.mu.RLock()
+	var matched []*Subscription
+	for _, sub := range bus.subs {
+		if sub.accepts(value) && matchTopic(sub.pattern, segments) {
+			matched = append(matched, sub)
+		}
+	}
+	bus.mu.RUnlock()
+
+	delivered := 0
+	for _, sub := range matched {
+		if sub.async {
+			if sub.enqueue(busEvent{topic: topic.name, value: value}) {
+				delivered++
+			}
+		} else {
+			bus.handle(sub, topic.name, value)
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// handle 呼叫訂閱者的處理函數並捕捉 panic
+func (b *Bus) handle(sub *Subscription, topic string, value interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			// 未設定 WithPanicHandler 時，panic 被捕捉後直接捨棄
+			if b.onPanic != nil {
+				b.onPanic(topic, newPanicError(r))
+			}
+		}
+	}()
+	sub.deliver(topic, value)
+}
+
+// enqueue 將事件放入非同步訂閱者的佇列，事件被捨棄或訂閱已取消時返回 false
+func (s *Subscription) enqueue(ev busEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for !s.closed && len(s.queue) >= s.size {
+		switch s.policy {
+		case OverflowDropNewest:
+			atomic.AddUint64(&s.dropped, 1)
+			return false
+		case OverflowDropOldest:
+			s.queue[0] = busEvent{}
+			s.queue = s.queue[1:]
+			atomic.AddUint64(&s.dropped, 1)
+		default:
+			s.cond.Wait()
+		}
+	}
+	if s.closed {
+		return false
+	}
+
+	s.queue = append(s.queue, ev)
+	s.cond.Broadcast()
+	return true
+}
+
+// loop 在獨立的 goroutine 中依序處理非同步訂閱者佇列中的事件
+func (s *Subscription) loop() {
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		ev := s.queue[0]
+		s.queue[0] = busEvent{}
+		s.queue = s.queue[1:]
+		s.cond.Broadcast()
+		s.mu.Unlock()
+
+		s.bus.handle(s, ev.topic, ev.value)
+	}
+}
+
+// Unsubscribe 取消訂閱，非同步訂閱者佇列中尚未處理的事件會被捨棄
+func (s *Subscription) Unsubscribe() {
+	s.bus.mu.Lock()
+	delete(s.bus.subs, s.id)
+	s.bus.mu.Unlock()
+
+	if s.async {
+		s.mu.Lock()
+		s.closed = true
+		s.queue = nil
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}
+}
+
+// Dropped 返回因佇列已滿而被捨棄的事件數量
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// matchTopic 判斷主題的各層級是否符合訂閱的 pattern
+func matchTopic(pattern, topic []string) bool {
+	if len(pattern) == 0 {
+		return len(topic) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		// "#" 可以符合零個或多個層級
+		for i := 0; i <= len(topic); i++ {
+			if matchTopic(pattern[1:], topic[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(topic) > 0 && matchTopic(pattern[1:], topic[1:])
+	default:
+		return len(topic) > 0 && pattern[0] == topic[0] && matchTopic(pattern[1:], topic[1:])
+	}
+}
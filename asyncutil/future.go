@@ -0,0 +1,88 @@
+package asyncutil
+
+import "sync"
+
+// Future 表示一個型別安全的異步結果，由型別化的函數創建
+type Future[T any] struct {
+	value T
+	err   error
+	done  chan struct{}
+	once  sync.Once
+}
+
+// newPendingFuture 創建一個尚未完成的 Future，由呼叫方負責呼叫 complete
+func newPendingFuture[T any]() *Future[T] {
+	return &Future[T]{
+		done: make(chan struct{}),
+	}
+}
+
+// complete 設定 Future 的結果，只有第一次呼叫會生效
+func (f *Future[T]) complete(value T, err error) {
+	f.once.Do(func() {
+		f.value = value
+		f.err = err
+		close(f.done)
+	})
+}
+
+// NewFuture 在新的 goroutine 中執行 fn，並返回代表其結果的 Future
+// fn 中發生的 panic 會被捕捉，並以 *PanicError 作為錯誤返回
+func NewFuture[T any](fn func() (T, error)) *Future[T] {
+	f := newPendingFuture[T]()
+
+	go func() {
+		value, err := recoverCall(fn)
+		f.complete(value, err)
+	}()
+
+	return f
+}
+
+// Await 等待結果，返回值和 error
+func (f *Future[T]) Await() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// Done 返回一個在 Future 完成時關閉的通道
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Pair 保存 Future2 的兩個返回值
+type Pair[T, U any] struct {
+	First  T
+	Second U
+}
+
+// Future2 表示具有兩個返回值（外加 error）的型別安全異步結果
+type Future2[T, U any] struct {
+	future *Future[Pair[T, U]]
+}
+
+// NewFuture2 在新的 goroutine 中執行 fn，並返回代表其結果的 Future2
+func NewFuture2[T, U any](fn func() (T, U, error)) *Future2[T, U] {
+	return &Future2[T, U]{
+		future: NewFuture(func() (Pair[T, U], error) {
+			first, second, err := fn()
+			return Pair[T, U]{First: first, Second: second}, err
+		}),
+	}
+}
+
+// Await 等待結果，返回兩個值和 error
+func (f *Future2[T, U]) Await() (T, U, error) {
+	p, err := f.future.Await()
+	return p.First, p.Second, err
+}
+
+// Done 返回一個在 Future2 完成時關閉的通道
+func (f *Future2[T, U]) Done() <-chan struct{} {
+	return f.future.Done()
+}
+
+// Future 返回以 Pair 保存兩個返回值的 Future，可傳給 AwaitAll、Race 等需要 Awaiter 的組合函數
+func (f *Future2[T, U]) Future() *Future[Pair[T, U]] {
+	return f.future
+}
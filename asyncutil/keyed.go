@@ -0,0 +1,178 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// ErrExecutorClosed 表示 KeyedExecutor 已關閉，不再接受新任務
+var ErrExecutorClosed = errors.New("asyncutil: keyed executor is closed")
+
+// KeyedError 表示 KeyedExecutor 中某個鍵的任務失敗
+type KeyedError struct {
+	Key string // 任務的鍵
+	Err error  // 任務返回的錯誤，發生 panic 時為 *PanicError
+}
+
+// Error 實作 error 介面
+func (e *KeyedError) Error() string {
+	return fmt.Sprintf("asyncutil: key %q: %v", e.Key, e.Err)
+}
+
+// Unwrap 返回任務的錯誤
+func (e *KeyedError) Unwrap() error {
+	return e.Err
+}
+
+// KeyedExecutor 將任務依鍵的雜湊值分配到固定數量的通道（lane）中執行：
+// 相同鍵的任務在同一個通道中依提交順序逐一執行，不同通道的任務平行執行。
+type KeyedExecutor struct {
+	mu         sync.Mutex
+	lanes      []chan func()
+	closed     bool
+	closing    chan struct{}  // Close 時關閉，喚醒等待通道空位的提交者
+	senders    sync.WaitGroup // 正在等待將任務放入通道的提交者，通道需在它們離開後才能關閉
+	observer   Observer
+	errHandler func(err error)
+	progress   *ProgressTracker
+	wg         sync.WaitGroup
+}
+
+// NewKeyedExecutor 創建一個具有 lanes 個通道、每個通道最多排隊 queueSize 個任務的 KeyedExecutor。
+// 可使用 WithErrorHandler 接收每個失敗任務的 *KeyedError、WithObserver 監控任務（ID 為任務的鍵），以及 WithProgress 追蹤進度。
+func NewKeyedExecutor(lanes, queueSize int, opts ...Option) *KeyedExecutor {
+	if lanes < 1 {
+		panic("NewKeyedExecutor: lanes must be at least 1")
+	}
+	if queueSize < 0 {
+		panic("NewKeyedExecutor: queueSize must not be negative")
+	}
+
+	o := buildOptions(opts)
+	e := &KeyedExecutor{
+		lanes:      make([]chan func(), lanes),
+		closing:    make(chan struct{}),
+		observer:   o.observer,
+		errHandler: o.errorHandler,
+		progress:   o.progress,
+	}
+
+	e.wg.Add(lanes)
+	for i := range e.lanes {
+		e.lanes[i] = make(chan func(), queueSize)
+		go e.lane(e.lanes[i])
+	}
+	return e
+}
+
+// Submit 將 fn 提交到 key 所屬的通道，並返回代表其結果的 Awaitable
+func (e *KeyedExecutor) Submit(key string, fn interface{}, args ...interface{}) (*Awaitable, error) {
+	return e.SubmitCtx(context.Background(), key, fn, args...)
+}
+
+// SubmitCtx 與 Submit 相同，但在等待通道空位時會因 ctx 取消而返回 ctx 的錯誤。
+// 若 fn 的第一個參數是 context.Context，會傳入 ctx；ctx 在任務開始前被取消時，任務不會執行。
+// 任務失敗不會影響同一個鍵或其他鍵後續的任務。
+func (e *KeyedExecutor) SubmitCtx(ctx context.Context, key string, fn interface{}, args ...interface{}) (*Awaitable, error) {
+	f := newPendingFuture[[]interface{}]()
+	ob := observe(e.observer, key)
+	step := e.progress.Step()
+	ctx = withProgressStep(ctx, step)
+	job := func() {
+		defer step.Done()
+		if err := ctx.Err(); err != nil {
+			ob.finish(err)
+			f.complete(nil, err)
+			return
+		}
+		ob.start()
+		results, err := recoverCall(func() ([]interface{}, error) {
+			return callFunc(ctx, fn, args)
+		})
+		ob.finish(err)
+		if err != nil && e.errHandler != nil {
+			e.errHandler(&KeyedError{Key: key, Err: err})
+		}
+		f.complete(results, err)
+	}
+
+	if err := e.enqueue(ctx, key, job); err != nil {
+		ob.finish(err)
+		step.Done()
+		return nil, err
+	}
+	return &Awaitable{future: f}, nil
+}
+
+// enqueue 將任務放入 key 所屬的通道，通道已滿時阻塞直到出現空位、ctx 被取消或 KeyedExecutor 關閉。
+// 等待期間不持有鎖，因此 Close 不會被阻塞中的提交者卡住。
+func (e *KeyedExecutor) enqueue(ctx context.Context, key string, job func()) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return ErrExecutorClosed
+	}
+	e.senders.Add(1)
+	e.mu.Unlock()
+	defer e.senders.Done()
+
+	select {
+	case e.lanes[e.laneIndex(key)] <- job:
+		return nil
+	case <-e.closing:
+		return ErrExecutorClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// laneIndex 返回 key 所屬通道的索引
+func (e *KeyedExecutor) laneIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(e.lanes)))
+}
+
+// lane 依序執行通道中的任務，直到通道被關閉且清空
+func (e *KeyedExecutor) lane(jobs chan func()) {
+	defer e.wg.Done()
+	for job := range jobs {
+		job()
+	}
+}
+
+// Close 停止接受新任務，並等待所有已提交的任務完成；正在等待通道空位的 Submit 會返回 ErrExecutorClosed。
+// 若 ctx 在完成前被取消，返回 ctx 的錯誤，已提交的任務仍會在背景繼續執行。重複呼叫 Close 是安全的。
+func (e *KeyedExecutor) Close(ctx context.Context) error {
+	e.mu.Lock()
+	first := !e.closed
+	if first {
+		e.closed = true
+		close(e.closing)
+	}
+	e.mu.Unlock()
+
+	if first {
+		// 提交者在 closing 關閉後會立即離開，之後才能安全地關閉通道
+		e.senders.Wait()
+		for _, jobs := range e.lanes {
+			close(jobs)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,81 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedExecutorOrderPerKey(t *testing.T) {
+	e := NewKeyedExecutor(4, 16)
+
+	var mu sync.Mutex
+	got := make(map[string][]int)
+	for i := 0; i < 20; i++ {
+		key := []string{"a", "b"}[i%2]
+		n := i
+		if _, err := e.Submit(key, func() {
+			mu.Lock()
+			got[key] = append(got[key], n)
+			mu.Unlock()
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, ns := range got {
+		for i := 1; i < len(ns); i++ {
+			if ns[i] < ns[i-1] {
+				t.Errorf("key %s ran out of order: %v", key, ns)
+				break
+			}
+		}
+	}
+}
+
+func TestKeyedExecutorCloseWithBlockedSubmit(t *testing.T) {
+	e := NewKeyedExecutor(1, 0)
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{})
+	if _, err := e.Submit("k", func() { close(started); <-release }); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// 通道已滿，這個提交會阻塞
+	submitted := make(chan error, 1)
+	go func() {
+		_, err := e.Submit("k", func() {})
+		submitted <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := e.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close = %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Close returned after %v, want shortly after its deadline", d)
+	}
+
+	select {
+	case err := <-submitted:
+		if !errors.Is(err, ErrExecutorClosed) {
+			t.Errorf("blocked Submit = %v, want ErrExecutorClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("blocked Submit did not return after Close")
+	}
+	if _, err := e.Submit("k", func() {}); !errors.Is(err, ErrExecutorClosed) {
+		t.Errorf("Submit after Close = %v, want ErrExecutorClosed", err)
+	}
+}
@@ -0,0 +1,185 @@
+package asyncutil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets 是 NewMetricsCollector 未指定區間時使用的執行時間直方圖上限
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// MetricsCollector 是一個在記憶體中統計任務數量、執行時間和執行中任務數量的 Observer
+type MetricsCollector struct {
+	mu        sync.Mutex
+	queued    int64
+	inFlight  int64
+	started   uint64
+	succeeded uint64
+	failed    uint64
+	panicked  uint64
+	abandoned uint64
+	bounds    []time.Duration
+	counts    []uint64 // 每個區間的數量，最後一個為超過所有上限的數量
+	sum       time.Duration
+}
+
+// MetricsSnapshot 是 MetricsCollector 在某個時間點的統計數據
+type MetricsSnapshot struct {
+	Queued    int64            // 已提交但尚未開始執行的任務數量
+	InFlight  int64            // 正在執行的任務數量
+	Started   uint64           // 已開始執行的任務總數
+	Succeeded uint64           // 執行成功的任務總數
+	Failed    uint64           // 執行失敗的任務總數，包含發生 panic 的任務
+	Panicked  uint64           // 發生 panic 的任務總數
+	Abandoned uint64           // 未執行即因取消、限流或關閉而結束的任務總數
+	Latency   LatencyHistogram // 已執行任務的執行時間分佈
+}
+
+// LatencyHistogram 是執行時間的直方圖
+type LatencyHistogram struct {
+	Bounds []time.Duration // 每個區間的上限（包含），依遞增排序
+	Counts []uint64        // 每個區間的數量，長度比 Bounds 多 1，最後一個為超過所有上限的數量
+	Count  uint64          // 總數量
+	Sum    time.Duration   // 執行時間總和
+}
+
+// NewMetricsCollector 創建一個 MetricsCollector，buckets 為直方圖每個區間的上限，未指定時使用 DefaultLatencyBuckets
+func NewMetricsCollector(buckets ...time.Duration) *MetricsCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	bounds := append([]time.Duration(nil), buckets...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	return &MetricsCollector{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// OnQueued 實作 Observer
+func (c *MetricsCollector) OnQueued(id string) {
+	c.mu.Lock()
+	c.queued++
+	c.mu.Unlock()
+}
+
+// OnStart 實作 Observer
+func (c *MetricsCollector) OnStart(id string) {
+	c.mu.Lock()
+	c.queued--
+	c.inFlight++
+	c.started++
+	c.mu.Unlock()
+}
+
+// OnFinish 實作 Observer
+func (c *MetricsCollector) OnFinish(event TaskEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !event.Started {
+		c.queued--
+		c.abandoned++
+		return
+	}
+
+	c.inFlight--
+	switch {
+	case event.Panic != nil:
+		c.failed++
+		c.panicked++
+	case event.Err != nil:
+		c.failed++
+	default:
+		c.succeeded++
+	}
+
+	i := sort.Search(len(c.bounds), func(i int) bool { return event.Duration <= c.bounds[i] })
+	c.counts[i]++
+	c.sum += event.Duration
+}
+
+// Snapshot 返回目前的統計數據
+func (c *MetricsCollector) Snapshot() MetricsSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := MetricsSnapshot{
+		Queued:    c.queued,
+		InFlight:  c.inFlight,
+		Started:   c.started,
+		Succeeded: c.succeeded,
+		Failed:    c.failed,
+		Panicked:  c.panicked,
+		Abandoned: c.abandoned,
+		Latency: LatencyHistogram{
+			Bounds: append([]time.Duration(nil), c.bounds...),
+			Counts: append([]uint64(nil), c.counts...),
+			Sum:    c.sum,
+		},
+	}
+	for _, n := range c.counts {
+		snapshot.Latency.Count += n
+	}
+	return snapshot
+}
+
+// WriteText 以 Prometheus 文字格式將統計數據寫入 w，每個指標名稱以 prefix 開頭，prefix 為空時使用 "asyncutil"
+func (c *MetricsCollector) WriteText(w io.Writer, prefix string) error {
+	if prefix == "" {
+		prefix = "asyncutil"
+	}
+	s := c.Snapshot()
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "# HELP %s_tasks_queued Tasks submitted but not yet started.\n", prefix)
+	fmt.Fprintf(bw, "# TYPE %s_tasks_queued gauge\n", prefix)
+	fmt.Fprintf(bw, "%s_tasks_queued %d\n", prefix, s.Queued)
+	fmt.Fprintf(bw, "# HELP %s_tasks_in_flight Tasks currently running.\n", prefix)
+	fmt.Fprintf(bw, "# TYPE %s_tasks_in_flight gauge\n", prefix)
+	fmt.Fprintf(bw, "%s_tasks_in_flight %d\n", prefix, s.InFlight)
+
+	fmt.Fprintf(bw, "# HELP %s_tasks_total Finished tasks by result.\n", prefix)
+	fmt.Fprintf(bw, "# TYPE %s_tasks_total counter\n", prefix)
+	fmt.Fprintf(bw, "%s_tasks_total{result=\"succeeded\"} %d\n", prefix, s.Succeeded)
+	fmt.Fprintf(bw, "%s_tasks_total{result=\"failed\"} %d\n", prefix, s.Failed-s.Panicked)
+	fmt.Fprintf(bw, "%s_tasks_total{result=\"panicked\"} %d\n", prefix, s.Panicked)
+	fmt.Fprintf(bw, "%s_tasks_total{result=\"abandoned\"} %d\n", prefix, s.Abandoned)
+
+	fmt.Fprintf(bw, "# HELP %s_task_duration_seconds Task execution time.\n", prefix)
+	fmt.Fprintf(bw, "# TYPE %s_task_duration_seconds histogram\n", prefix)
+	var cumulative uint64
+	for i, bound := range s.Latency.Bounds {
+		cumulative += s.Latency.Counts[i]
+		fmt.Fprintf(bw, "%s_task_duration_seconds_bucket{le=\"%s\"} %d\n", prefix, formatSeconds(bound), cumulative)
+	}
+	fmt.Fprintf(bw, "%s_task_duration_seconds_bucket{le=\"+Inf\"} %d\n", prefix, s.Latency.Count)
+	fmt.Fprintf(bw, "%s_task_duration_seconds_sum %s\n", prefix, formatSeconds(s.Latency.Sum))
+	fmt.Fprintf(bw, "%s_task_duration_seconds_count %d\n", prefix, s.Latency.Count)
+
+	return bw.Flush()
+}
+
+// formatSeconds 將時間長度格式化為以秒為單位的數字
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
@@ -0,0 +1,136 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Observer 接收任務生命週期的事件，可用於記錄日誌或收集指標。
+// 每個 OnQueued 之後都會有且只有一次 OnFinish；OnStart 只在任務真正開始執行時呼叫。
+// 方法可能被多個 goroutine 同時呼叫，實作需自行處理同步，且不應長時間阻塞。
+type Observer interface {
+	// OnQueued 在任務被提交、尚未開始執行時呼叫
+	OnQueued(id string)
+	// OnStart 在任務開始執行時呼叫
+	OnStart(id string)
+	// OnFinish 在任務結束或未執行即被放棄時呼叫
+	OnFinish(event TaskEvent)
+}
+
+// TaskEvent 描述一個已結束的任務
+type TaskEvent struct {
+	ID       string        // 任務的標識符，透過 Pool.Submit 或 AsyncCtx 提交的任務為空字串
+	Started  bool          // 任務是否曾開始執行，因取消、限流或關閉而未執行時為 false
+	Wait     time.Duration // 從提交到開始執行（或被放棄）的時間
+	Duration time.Duration // 執行時間，未開始執行時為 0
+	Err      error         // 任務的錯誤
+	Panic    *PanicError   // 任務發生 panic 時的值和堆疊追蹤，否則為 nil
+}
+
+// observerKey 是在 context 中保存 Observer 的鍵
+type observerKey struct{}
+
+// ContextWithObserver 返回帶有 obs 的 context，AsyncCtx 會將透過此 context 執行的函數回報給 obs
+func ContextWithObserver(ctx context.Context, obs Observer) context.Context {
+	return context.WithValue(ctx, observerKey{}, obs)
+}
+
+// ObserverFromContext 返回 ctx 中的 Observer，沒有時返回 nil
+func ObserverFromContext(ctx context.Context) Observer {
+	obs, _ := ctx.Value(observerKey{}).(Observer)
+	return obs
+}
+
+// WithObserver 讓 ParallelProcess、RunDAG 和 Pool 將每個任務的生命週期事件回報給 obs
+func WithObserver(obs Observer) Option {
+	return func(o *options) {
+		o.observer = obs
+	}
+}
+
+// observation 追蹤單一任務的生命週期，保證 OnFinish 只被呼叫一次。
+// 所有方法都可以在 nil 上呼叫，未設定 Observer 時不做任何事。
+type observation struct {
+	obs      Observer
+	id       string
+	mu       sync.Mutex
+	queuedAt time.Time
+	startAt  time.Time
+	started  bool
+	finished bool
+}
+
+// observe 呼叫 obs.OnQueued 並返回追蹤此任務的 observation，obs 為 nil 時返回 nil
+func observe(obs Observer, id string) *observation {
+	if obs == nil {
+		return nil
+	}
+	obs.OnQueued(id)
+	return &observation{obs: obs, id: id, queuedAt: time.Now()}
+}
+
+// observeAll 為每個任務呼叫 observe，返回的切片長度與 tasks 相同
+func (o *options) observeAll(tasks []Task) []*observation {
+	observations := make([]*observation, len(tasks))
+	if o.observer == nil {
+		return observations
+	}
+	for i, task := range tasks {
+		observations[i] = observe(o.observer, task.ID)
+	}
+	return observations
+}
+
+// start 將任務標記為開始執行並呼叫 OnStart，任務已結束時不做任何事
+func (ob *observation) start() {
+	if ob == nil {
+		return
+	}
+	ob.mu.Lock()
+	if ob.started || ob.finished {
+		ob.mu.Unlock()
+		return
+	}
+	ob.started = true
+	ob.startAt = time.Now()
+	ob.mu.Unlock()
+
+	ob.obs.OnStart(ob.id)
+}
+
+// finish 將任務標記為結束並呼叫 OnFinish，重複呼叫時只有第一次有效
+func (ob *observation) finish(err error) {
+	ob.end(err, false)
+}
+
+// abandon 在任務尚未開始執行時以 err 結束任務，已開始的任務留待執行結束時回報
+func (ob *observation) abandon(err error) {
+	ob.end(err, true)
+}
+
+// end 結束任務並呼叫 OnFinish，onlyPending 為 true 時已開始的任務不受影響
+func (ob *observation) end(err error, onlyPending bool) {
+	if ob == nil {
+		return
+	}
+	ob.mu.Lock()
+	if ob.finished || (onlyPending && ob.started) {
+		ob.mu.Unlock()
+		return
+	}
+	ob.finished = true
+	event := TaskEvent{ID: ob.id, Started: ob.started, Err: err}
+	now := time.Now()
+	if ob.started {
+		event.Wait = ob.startAt.Sub(ob.queuedAt)
+		event.Duration = now.Sub(ob.startAt)
+	} else {
+		event.Wait = now.Sub(ob.queuedAt)
+	}
+	ob.mu.Unlock()
+
+	errors.As(err, &event.Panic)
+	ob.obs.OnFinish(event)
+}
@@ -0,0 +1,265 @@
+package asyncutil
+
+import "time"
+
+// Option 用於設定 asyncutil 中各種執行函數的行為
+type Option func(*options)
+
+// options 保存所有可設定的選項，各個執行函數只會讀取與自己相關的欄位
+type options struct {
+	failFast       bool // 第一個任務失敗時中止其餘任務
+	rejectWhenFull bool // Pool 佇列已滿時拒絕提交而不是阻塞
+	concurrency    int  // 同時執行的任務或 goroutine 數量上限（平行迴圈中為線程數），0 表示使用預設值
+	grainSize      int  // 平行迴圈每次分配的批次大小，0 表示自動計算
+	orderedWindow  int  // ParallelProcessStream 依提交順序輸出時的重排緩衝大小，0 表示依完成順序輸出
+
+	rateLimiter  *RateLimiter           // 執行每個任務前需取得的令牌桶
+	keyedLimiter *KeyedRateLimiter      // 依鍵區分的令牌桶
+	rateLimitKey func(task Task) string // 從任務取得 keyedLimiter 的鍵，nil 表示使用 Task.ID
+
+	dedup     *Group        // 以 Task.ID 合併相同任務的執行
+	resultTTL time.Duration // Group 成功結果的快取時間
+
+	clock    Clock         // 時間來源，nil 表示使用系統時間
+	edgesSet bool          // 是否設定了 WithEdges
+	leading  bool          // Debounce 和 Throttle 是否在前端執行
+	trailing bool          // Debounce 和 Throttle 是否在尾端執行
+	maxWait  time.Duration // Debounce 的最長等待時間
+
+	location      *time.Location   // Scheduler 計算 cron 表達式使用的時區
+	resultHandler func(TaskResult) // 接收 Scheduler 每次執行的結果
+
+	asyncBuffer    int                                 // 非同步訂閱者的佇列大小，0 表示同步處理
+	overflowPolicy OverflowPolicy                      // 非同步訂閱者的佇列已滿時的處理方式
+	panicHandler   func(topic string, err *PanicError) // 接收訂閱者處理事件時發生的 panic
+
+	observer Observer      // 接收任務生命週期事件
+	aging    time.Duration // Pool 中的任務每等待此時間，優先順序視為提高 1，0 表示不提高
+	sem      *Semaphore    // Pool 執行每個任務前需取得 Task.Weight 權重的信號量
+
+	errorHandler     func(err error) // 接收背景執行的函數返回的錯誤
+	flushConcurrency int             // Batcher 同時執行的 flush 數量上限，0 表示使用預設值 1
+	joinErrors       bool            // Scope.Wait 返回所有錯誤而不是第一個錯誤
+	maxAttempts      int             // DurableQueue 的工作失敗幾次後移到死信佇列
+	workers          int             // DurableQueue.Process 的 worker 數量，0 表示使用預設值 1
+
+	progress *ProgressTracker // 接收工作量和完成進度
+}
+
+// clockOrDefault 返回設定的 Clock，未設定時返回 SystemClock
+func (o *options) clockOrDefault() Clock {
+	if o.clock == nil {
+		return SystemClock()
+	}
+	return o.clock
+}
+
+// buildOptions 套用所有 Option 並返回設定結果
+func buildOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithFailFast 讓 ParallelProcess 在第一個任務返回錯誤或發生 panic 時中止其餘任務，
+// 被中止的任務會在 TaskResult.Err 中記錄 ErrAborted
+func WithFailFast() Option {
+	return func(o *options) {
+		o.failFast = true
+	}
+}
+
+// WithCollectAll 讓 ParallelProcess 等待所有任務完成並收集所有結果，這是預設行為
+func WithCollectAll() Option {
+	return func(o *options) {
+		o.failFast = false
+	}
+}
+
+// WithConcurrency 限制同時執行的數量，各函數的意義和預設值如下：
+//   - ParallelProcess、ParallelProcessStream 和 RunDAG：同時執行的任務數量，n <= 0 表示不限制。
+//   - NewScope：同時執行的 goroutine 數量，n <= 0 表示不限制。
+//   - 平行迴圈（ParallelForWith、ParallelMap 等泛型平行函數和 ParallelRange）：線程數，n <= 0 表示使用 CPU 核心數。
+//
+// Batcher 和 DurableQueue.Process 不使用此選項，請分別使用 WithFlushConcurrency 和 WithWorkers。
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+// WithGrainSize 指定平行迴圈每次分配給線程的元素數量，n <= 0 表示自動計算。
+// 較小的批次能讓執行時間不均的元素更平均地分配到各線程。
+func WithGrainSize(n int) Option {
+	return func(o *options) {
+		o.grainSize = n
+	}
+}
+
+// WithOrdered 讓 ParallelProcessStream 依提交順序輸出結果，window 為最多可以尚未輸出的任務數量，
+// 排在前面的任務尚未完成時，最多只會再啟動 window 個任務，小於 1 時視為 1
+func WithOrdered(window int) Option {
+	return func(o *options) {
+		if window < 1 {
+			window = 1
+		}
+		o.orderedWindow = window
+	}
+}
+
+// WithRejectWhenFull 讓 Pool 在佇列已滿時立即返回 ErrPoolFull，而不是阻塞提交者
+func WithRejectWhenFull() Option {
+	return func(o *options) {
+		o.rejectWhenFull = true
+	}
+}
+
+// WithBlockWhenFull 讓 Pool 在佇列已滿時阻塞提交者直到出現空位，這是預設行為
+func WithBlockWhenFull() Option {
+	return func(o *options) {
+		o.rejectWhenFull = false
+	}
+}
+
+// WithAging 讓 Pool 中排隊的任務每等待 d 時間，優先順序就視為提高 1，避免低優先順序的任務一直無法執行
+func WithAging(d time.Duration) Option {
+	return func(o *options) {
+		if d < 0 {
+			d = 0
+		}
+		o.aging = d
+	}
+}
+
+// WithSemaphore 讓 Pool 在執行每個任務前從 sem 取得 Task.Weight 的權重（未指定權重或透過 Submit 提交的任務為 1），
+// 任務結束後歸還，用於依資源用量而不是任務數量限制並行
+func WithSemaphore(sem *Semaphore) Option {
+	return func(o *options) {
+		o.sem = sem
+	}
+}
+
+// WithRateLimiter 讓 ParallelProcess、RunDAG 和 Pool 在執行每個任務前先從 limiter 取得令牌
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(o *options) {
+		o.rateLimiter = limiter
+	}
+}
+
+// WithKeyedRateLimiter 讓 ParallelProcess、RunDAG 和 Pool 在執行每個任務前先從 key(task) 對應的令牌桶取得令牌，
+// key 為 nil 時使用 Task.ID 作為鍵。透過 Pool.Submit 提交的任務沒有 Task，key 會收到零值的 Task。
+func WithKeyedRateLimiter(limiter *KeyedRateLimiter, key func(task Task) string) Option {
+	return func(o *options) {
+		o.keyedLimiter = limiter
+		o.rateLimitKey = key
+	}
+}
+
+// WithDedup 讓 ParallelProcess 以 Task.ID 作為鍵，透過 group 合併相同 ID 任務的執行，
+// 相同 ID 的任務只會執行一次，所有任務都會收到相同的 TaskResult；ID 為空字串的任務不會被合併。
+// 共用的執行不會因發起它的呼叫方取消（包括 WithFailFast）而中止，各呼叫方在自己的 ctx 結束時停止等待。
+func WithDedup(group *Group) Option {
+	return func(o *options) {
+		o.dedup = group
+	}
+}
+
+// WithResultTTL 讓 NewGroup 創建的 Group 在執行成功後將結果快取 ttl 的時間
+func WithResultTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.resultTTL = ttl
+	}
+}
+
+// WithClock 替換 Debounce、Throttle 等函數使用的時間來源，例如在測試中使用 ManualClock
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithEdges 設定 Debounce 和 Throttle 是否在一連串呼叫的前端（leading）和尾端（trailing）執行
+func WithEdges(leading, trailing bool) Option {
+	return func(o *options) {
+		o.edgesSet = true
+		o.leading = leading
+		o.trailing = trailing
+	}
+}
+
+// WithMaxWait 設定 Debounce 的最長等待時間，持續有新呼叫時，尾端呼叫最多延遲 d 就會執行一次
+func WithMaxWait(d time.Duration) Option {
+	return func(o *options) {
+		o.maxWait = d
+	}
+}
+
+// WithLocation 指定 Scheduler 計算 cron 表達式時使用的時區
+func WithLocation(loc *time.Location) Option {
+	return func(o *options) {
+		o.location = loc
+	}
+}
+
+// WithResultHandler 讓 Scheduler 在每次執行工作後以執行結果呼叫 handler
+func WithResultHandler(handler func(TaskResult)) Option {
+	return func(o *options) {
+		o.resultHandler = handler
+	}
+}
+
+// WithAsyncDelivery 讓 Subscribe 創建的訂閱者透過大小為 buffer 的佇列在獨立的 goroutine 中處理事件，
+// 佇列已滿時依照 policy 處理；buffer 小於 1 時視為 1
+func WithAsyncDelivery(buffer int, policy OverflowPolicy) Option {
+	return func(o *options) {
+		if buffer < 1 {
+			buffer = 1
+		}
+		o.asyncBuffer = buffer
+		o.overflowPolicy = policy
+	}
+}
+
+// WithPanicHandler 讓 NewBus 創建的 Bus 在訂閱者處理事件發生 panic 時呼叫 handler
+func WithPanicHandler(handler func(topic string, err *PanicError)) Option {
+	return func(o *options) {
+		o.panicHandler = handler
+	}
+}
+
+// WithErrorHandler 讓 Batcher 等在背景執行函數的類型在函數返回錯誤或發生 panic 時呼叫 handler
+func WithErrorHandler(handler func(err error)) Option {
+	return func(o *options) {
+		o.errorHandler = handler
+	}
+}
+
+// WithFlushConcurrency 設定 Batcher 同時執行的 flush 函數數量上限，n <= 0 表示使用預設值 1（依批次組成順序逐一處理）
+func WithFlushConcurrency(n int) Option {
+	return func(o *options) {
+		o.flushConcurrency = n
+	}
+}
+
+// WithJoinErrors 讓 Scope.Wait 以 errors.Join 返回所有 goroutine 的錯誤，而不是只返回第一個錯誤
+func WithJoinErrors() Option {
+	return func(o *options) {
+		o.joinErrors = true
+	}
+}
+
+// WithMaxAttempts 讓 OpenDurableQueue 開啟的佇列在工作失敗 n 次後將其移到死信佇列
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithWorkers 設定 DurableQueue.Process 同時處理工作的 worker 數量，n <= 0 表示使用預設值 1
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
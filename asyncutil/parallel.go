@@ -0,0 +1,212 @@
+package asyncutil
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// batchesPerGoroutine 是未指定批次大小時，每個線程平均分到的批次數量
+// 批次越小，負載越平均，但分配批次的開銷也越大
+const batchesPerGoroutine = 8
+
+// parallelBatches 將 [0, n) 切分成小批次，由多個線程動態領取並執行 fn(begin, end)。
+// 執行較快的線程會領取更多批次，避免少數較慢的元素拖慢整體進度。
+func parallelBatches(n int, o *options, fn func(begin, end int)) {
+	if n <= 0 {
+		return
+	}
+	o.progress.AddTotal(float64(n))
+
+	goroutines := o.concurrency
+	if goroutines <= 0 {
+		goroutines = getDefaultGoroutines()
+	}
+
+	grain := o.grainSize
+	if grain <= 0 {
+		grain = n / (goroutines * batchesPerGoroutine)
+		if grain < 1 {
+			grain = 1
+		}
+	}
+
+	// 線程數不需要超過批次數量
+	batches := (n + grain - 1) / grain
+	if goroutines > batches {
+		goroutines = batches
+	}
+
+	var next int64
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				begin := int(atomic.AddInt64(&next, int64(grain))) - grain
+				if begin >= n {
+					return
+				}
+				end := begin + grain
+				if end > n {
+					end = n
+				}
+				fn(begin, end)
+				o.progress.Advance(float64(end - begin))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// sortedMapKeys 返回 map 的所有鍵，若鍵是數字或字串類型則排序，使結果順序穩定
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	if len(keys) == 0 {
+		return keys
+	}
+
+	var less func(a, b reflect.Value) bool
+	switch keys[0].Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less = func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		less = func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	case reflect.Float32, reflect.Float64:
+		less = func(a, b reflect.Value) bool { return a.Float() < b.Float() }
+	case reflect.String:
+		less = func(a, b reflect.Value) bool { return a.String() < b.String() }
+	default:
+		return keys
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+	return keys
+}
+
+// ParallelMap 平行地對切片中的每個元素執行 fn，並依照原順序返回結果
+func ParallelMap[T, R any](data []T, fn func(T) R, opts ...Option) []R {
+	results := make([]R, len(data))
+	parallelBatches(len(data), buildOptions(opts), func(begin, end int) {
+		for i := begin; i < end; i++ {
+			results[i] = fn(data[i])
+		}
+	})
+	return results
+}
+
+// ParallelRange 平行地對 start 到 end（不包括）之間每隔 step 的整數執行 task，並依迭代順序返回結果。
+// step 可以是負數（此時 start 應大於 end），step 為 0 時會 panic。
+// 可使用 WithConcurrency(n) 指定線程數、WithGrainSize(n) 指定每個批次的迭代次數。
+func ParallelRange[R any](start, end, step int, task func(i int) R, opts ...Option) []R {
+	if step == 0 {
+		panic("ParallelRange: step must not be zero")
+	}
+
+	n := 0
+	switch {
+	case step > 0 && end > start:
+		n = (end - start + step - 1) / step
+	case step < 0 && start > end:
+		n = (start - end - step - 1) / -step
+	}
+
+	results := make([]R, n)
+	parallelBatches(n, buildOptions(opts), func(begin, end int) {
+		for k := begin; k < end; k++ {
+			results[k] = task(start + k*step)
+		}
+	})
+	return results
+}
+
+// ParallelFilter 平行地對切片中的每個元素執行 pred，並依照原順序返回 pred 為 true 的元素
+func ParallelFilter[T any](data []T, pred func(T) bool, opts ...Option) []T {
+	keep := make([]bool, len(data))
+	parallelBatches(len(data), buildOptions(opts), func(begin, end int) {
+		for i := begin; i < end; i++ {
+			keep[i] = pred(data[i])
+		}
+	})
+
+	results := make([]T, 0, len(data))
+	for i, v := range data {
+		if keep[i] {
+			results = append(results, v)
+		}
+	}
+	return results
+}
+
+// ParallelReduce 平行地以 combine 合併切片中的所有元素。
+// combine 必須滿足結合律，identity 必須是 combine 的單位元素（例如加法的 0）；
+// 每個批次先各自合併，再依照批次順序合併，因此 combine 不需要滿足交換律。
+func ParallelReduce[T any](data []T, identity T, combine func(T, T) T, opts ...Option) T {
+	type partial struct {
+		begin int
+		value T
+	}
+
+	var mu sync.Mutex
+	var partials []partial
+	parallelBatches(len(data), buildOptions(opts), func(begin, end int) {
+		acc := identity
+		for i := begin; i < end; i++ {
+			acc = combine(acc, data[i])
+		}
+
+		mu.Lock()
+		partials = append(partials, partial{begin: begin, value: acc})
+		mu.Unlock()
+	})
+
+	sort.Slice(partials, func(i, j int) bool {
+		return partials[i].begin < partials[j].begin
+	})
+
+	result := identity
+	for _, p := range partials {
+		result = combine(result, p.value)
+	}
+	return result
+}
+
+// ParallelMapKeyed 平行地對 map 中的每個鍵值對執行 fn，並返回以相同鍵保存結果的新 map
+func ParallelMapKeyed[K comparable, V, R any](m map[K]V, fn func(K, V) R, opts ...Option) map[K]R {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	values := ParallelMap(keys, func(k K) R {
+		return fn(k, m[k])
+	}, opts...)
+
+	results := make(map[K]R, len(keys))
+	for i, k := range keys {
+		results[k] = values[i]
+	}
+	return results
+}
+
+// ParallelFilterKeyed 平行地對 map 中的每個鍵值對執行 pred，並返回只包含 pred 為 true 的鍵值對的新 map
+func ParallelFilterKeyed[K comparable, V any](m map[K]V, pred func(K, V) bool, opts ...Option) map[K]V {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	kept := ParallelFilter(keys, func(k K) bool {
+		return pred(k, m[k])
+	}, opts...)
+
+	results := make(map[K]V, len(kept))
+	for _, k := range kept {
+		results[k] = m[k]
+	}
+	return results
+}
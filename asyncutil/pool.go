@@ -0,0 +1,411 @@
+package asyncutil
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPoolClosed 表示 Pool 已關閉，不再接受新任務，或排隊中的任務被 ShutdownNow 放棄
+	ErrPoolClosed = errors.New("asyncutil: pool is shut down")
+	// ErrPoolFull 表示 Pool 的佇列已滿，且設定了 WithRejectWhenFull
+	ErrPoolFull = errors.New("asyncutil: pool queue is full")
+	// ErrTaskNotQueued 表示指定 ID 的任務不在佇列中，可能已開始執行、已結束或不存在
+	ErrTaskNotQueued = errors.New("asyncutil: task is not queued")
+	// ErrTaskCancelled 表示排隊中的任務被 Pool.Cancel 取消
+	ErrTaskCancelled = errors.New("asyncutil: task cancelled")
+)
+
+// Pool 是一個固定數量 worker 的工作池，使用有界佇列保存待執行的任務。
+// 佇列中的任務依優先順序執行，相同優先順序時依提交順序執行。
+type Pool struct {
+	mu        sync.Mutex
+	cond      *sync.Cond    // 通知 worker 有新任務或 Pool 已關閉
+	queue     jobQueue      // 待執行的任務
+	queueSize int           // 佇列容量
+	seq       uint64        // 下一個任務的提交序號
+	start     time.Time     // Pool 創建的時間，用於計算老化後的優先順序
+	spaceCh   chan struct{} // 佇列出現空位或 Pool 關閉時關閉並重建，用於喚醒等待中的提交者
+	idle      int           // 正在等待任務的 worker 數量
+	closed    bool
+	opts      *options
+
+	ctx    context.Context // ShutdownNow 時取消，用於中止執行中的任務
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// poolJob 表示佇列中的一個任務
+type poolJob struct {
+	ctx     context.Context
+	run     func(ctx context.Context) // 在 worker 中執行任務並設定結果
+	abandon func(err error)           // 任務未執行即被放棄時設定結果
+
+	task       Task      // 提交的任務，用於計算 WithKeyedRateLimiter 的鍵；透過 Submit 提交時只有零值
+	id         string    // 任務的標識符，透過 Submit 提交的任務為空字串
+	priority   int       // 優先順序
+	weight     int64     // 從 WithSemaphore 設定的信號量取得的權重
+	enqueuedAt time.Time // 進入佇列的時間
+	seq        uint64    // 提交序號，優先順序相同時先提交的先執行
+	key        float64   // 考慮老化後的排序鍵，越大越先執行
+	index      int       // 在 heap 中的位置
+}
+
+// jobQueue 是以 poolJob.key 排序的最大堆積，實作 heap.Interface
+type jobQueue []*poolJob
+
+func (q jobQueue) Len() int { return len(q) }
+
+func (q jobQueue) Less(i, j int) bool {
+	if q[i].key != q[j].key {
+		return q[i].key > q[j].key
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q jobQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *jobQueue) Push(x interface{}) {
+	job := x.(*poolJob)
+	job.index = len(*q)
+	*q = append(*q, job)
+}
+
+func (q *jobQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	job := old[n-1]
+	old[n-1] = nil
+	job.index = -1
+	*q = old[:n-1]
+	return job
+}
+
+// NewPool 創建一個具有 workers 個 worker、佇列容量為 queueSize 的工作池。
+// 佇列已滿時預設會阻塞提交者，可使用 WithRejectWhenFull 改為直接返回 ErrPoolFull。
+func NewPool(workers, queueSize int, opts ...Option) *Pool {
+	if workers < 1 {
+		panic("NewPool: workers must be at least 1")
+	}
+	if queueSize < 0 {
+		panic("NewPool: queueSize must not be negative")
+	}
+
+	p := &Pool{
+		queueSize: queueSize,
+		start:     time.Now(),
+		spaceCh:   make(chan struct{}),
+		opts:      buildOptions(opts),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+
+	return p
+}
+
+// Submit 提交一個函數到工作池，並返回代表其結果的 Awaitable
+func (p *Pool) Submit(fn interface{}, args ...interface{}) (*Awaitable, error) {
+	return p.SubmitCtx(context.Background(), fn, args...)
+}
+
+// SubmitCtx 與 Submit 相同，但在等待佇列空位時會因 ctx 取消而返回 ctx 的錯誤。
+// 若 fn 的第一個參數是 context.Context，會傳入一個在 ctx 取消或 ShutdownNow 時取消的 context。
+func (p *Pool) SubmitCtx(ctx context.Context, fn interface{}, args ...interface{}) (*Awaitable, error) {
+	future, err := SubmitFuture(ctx, p, func(ctx context.Context) ([]interface{}, error) {
+		return callFunc(ctx, fn, args)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Awaitable{future: future}, nil
+}
+
+// SubmitFuture 提交一個型別化的函數到工作池，並返回代表其結果的 Future。
+// fn 收到的 context 會在 ctx 取消或 ShutdownNow 時取消。
+func SubmitFuture[T any](ctx context.Context, p *Pool, fn func(ctx context.Context) (T, error)) (*Future[T], error) {
+	return submit(ctx, p, Task{}, fn, nil)
+}
+
+// SubmitTask 提交一個 Task 到工作池，並返回代表其 TaskResult 的 Future。
+// 任務依 Task.Priority 排隊，排隊期間可透過 Task.ID 呼叫 SetPriority 或 Cancel。
+func (p *Pool) SubmitTask(task Task) (*Future[TaskResult], error) {
+	return p.SubmitTaskCtx(context.Background(), task)
+}
+
+// SubmitTaskCtx 與 SubmitTask 相同，但在等待佇列空位時會因 ctx 取消而返回 ctx 的錯誤。
+// 若任務函數的第一個參數是 context.Context，會傳入一個在 ctx 取消或 ShutdownNow 時取消的 context。
+// 任務會依照 Task.Retry 重試，發生 panic 時記錄在 TaskResult.Panic。
+func (p *Pool) SubmitTaskCtx(ctx context.Context, task Task) (*Future[TaskResult], error) {
+	return submit(ctx, p, task, func(ctx context.Context) (TaskResult, error) {
+		result := runTask(ctx, task)
+		return result, result.Err
+	}, func(err error) TaskResult {
+		return TaskResult{ID: task.ID, Err: err}
+	})
+}
+
+// submit 將 fn 放入佇列，並返回代表其結果的 Future，任務的標識符、優先順序和權重取自 task。
+// 任務未執行即結束時，以 abandoned(err) 作為結果，abandoned 為 nil 時使用零值。
+func submit[T any](ctx context.Context, p *Pool, task Task, fn func(ctx context.Context) (T, error), abandoned func(err error) T) (*Future[T], error) {
+	f := newPendingFuture[T]()
+	ob := observe(p.opts.observer, task.ID)
+	ctx, step := p.opts.progressStep(ctx)
+	abandon := func(err error) {
+		var value T
+		if abandoned != nil {
+			value = abandoned(err)
+		}
+		ob.finish(err)
+		step.Done()
+		f.complete(value, err)
+	}
+	job := &poolJob{
+		ctx: ctx,
+		run: func(ctx context.Context) {
+			if err := ctx.Err(); err != nil {
+				abandon(err)
+				return
+			}
+			ob.start()
+			value, err := recoverCall(func() (T, error) {
+				return fn(ctx)
+			})
+			ob.finish(err)
+			step.Done()
+			f.complete(value, err)
+		},
+		abandon:  abandon,
+		task:     task,
+		id:       task.ID,
+		priority: task.Priority,
+		weight:   task.Weight,
+	}
+
+	if err := p.enqueue(ctx, job); err != nil {
+		ob.finish(err)
+		step.Done()
+		return nil, err
+	}
+	return f, nil
+}
+
+// enqueue 將任務放入佇列，佇列已滿時依設定阻塞或拒絕
+func (p *Pool) enqueue(ctx context.Context, job *poolJob) error {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return ErrPoolClosed
+		}
+		// 空閒的 worker 會立即取走任務，因此不佔用佇列容量
+		if len(p.queue) < p.queueSize+p.idle {
+			job.enqueuedAt = time.Now()
+			job.seq = p.seq
+			p.seq++
+			job.key = p.keyLocked(job)
+			heap.Push(&p.queue, job)
+			p.cond.Signal()
+			p.mu.Unlock()
+			return nil
+		}
+		if p.opts.rejectWhenFull {
+			p.mu.Unlock()
+			return ErrPoolFull
+		}
+		spaceCh := p.spaceCh
+		p.mu.Unlock()
+
+		select {
+		case <-spaceCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// worker 持續從佇列取出任務並執行，直到 Pool 關閉且佇列為空
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for {
+		job, ok := p.next()
+		if !ok {
+			return
+		}
+		p.runJob(job)
+	}
+}
+
+// next 從佇列取出下一個任務，Pool 已關閉且佇列為空時返回 false
+func (p *Pool) next() (*poolJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.queue) == 0 && !p.closed {
+		p.idle++
+		p.cond.Wait()
+		p.idle--
+	}
+	if len(p.queue) == 0 {
+		return nil, false
+	}
+
+	job := heap.Pop(&p.queue).(*poolJob)
+	p.signalSpaceLocked()
+	return job, true
+}
+
+// keyLocked 計算任務的排序鍵。設定 WithAging 時，任務每等待 aging 時間優先順序視為提高 1，
+// 由於所有任務隨時間提高的幅度相同，排序鍵只需在進入佇列或改變優先順序時計算一次。
+func (p *Pool) keyLocked(job *poolJob) float64 {
+	key := float64(job.priority)
+	if p.opts.aging > 0 {
+		key -= float64(job.enqueuedAt.Sub(p.start)) / float64(p.opts.aging)
+	}
+	return key
+}
+
+// runJob 執行任務，任務的 context 會在提交時的 ctx 取消或 ShutdownNow 時取消
+func (p *Pool) runJob(job *poolJob) {
+	ctx, cancel := context.WithCancel(job.ctx)
+	stop := context.AfterFunc(p.ctx, cancel)
+	defer stop()
+	defer cancel()
+
+	if err := p.opts.waitRateLimit(ctx, job.task); err != nil {
+		job.abandon(err)
+		return
+	}
+	if p.opts.sem != nil {
+		weight := job.weight
+		if weight < 1 {
+			weight = 1
+		}
+		if err := p.opts.sem.Acquire(ctx, weight); err != nil {
+			job.abandon(err)
+			return
+		}
+		defer p.opts.sem.Release(weight)
+	}
+	job.run(ctx)
+}
+
+// signalSpaceLocked 喚醒所有等待佇列空位的提交者，呼叫方需持有鎖
+func (p *Pool) signalSpaceLocked() {
+	close(p.spaceCh)
+	p.spaceCh = make(chan struct{})
+}
+
+// SetPriority 改變佇列中所有標識符為 id 的任務的優先順序，沒有這樣的任務時返回 ErrTaskNotQueued
+func (p *Pool) SetPriority(id string, priority int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	found := false
+	for _, job := range p.queue {
+		if job.id != id {
+			continue
+		}
+		job.priority = priority
+		job.key = p.keyLocked(job)
+		found = true
+	}
+	if !found {
+		return ErrTaskNotQueued
+	}
+	heap.Init(&p.queue)
+	return nil
+}
+
+// Cancel 從佇列中移除所有標識符為 id 且尚未開始執行的任務，並以 ErrTaskCancelled 完成它們。
+// 沒有這樣的任務時返回 ErrTaskNotQueued，已開始執行的任務不受影響。
+func (p *Pool) Cancel(id string) error {
+	p.mu.Lock()
+	var cancelled []*poolJob
+	for _, job := range p.queue {
+		if job.id == id {
+			cancelled = append(cancelled, job)
+		}
+	}
+	for _, job := range cancelled {
+		heap.Remove(&p.queue, job.index)
+	}
+	if len(cancelled) > 0 {
+		p.signalSpaceLocked()
+	}
+	p.mu.Unlock()
+
+	if len(cancelled) == 0 {
+		return ErrTaskNotQueued
+	}
+	for _, job := range cancelled {
+		job.abandon(ErrTaskCancelled)
+	}
+	return nil
+}
+
+// QueueLen 返回目前在佇列中等待執行的任務數量
+func (p *Pool) QueueLen() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queue)
+}
+
+// Shutdown 停止接受新任務，並等待佇列中的任務和執行中的任務全部完成。
+// 若 ctx 在完成前被取消，返回 ctx 的錯誤，已提交的任務仍會在背景繼續執行。
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closeLocked()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ShutdownNow 停止接受新任務，放棄佇列中尚未開始的任務並取消執行中任務的 context。
+// 被放棄的任務會以 ErrPoolClosed 完成，返回值為被放棄的任務數量。
+func (p *Pool) ShutdownNow() int {
+	p.mu.Lock()
+	p.closeLocked()
+	abandoned := p.queue
+	p.queue = nil
+	p.mu.Unlock()
+
+	p.cancel()
+	for _, job := range abandoned {
+		job.abandon(ErrPoolClosed)
+	}
+	return len(abandoned)
+}
+
+// closeLocked 將 Pool 標記為已關閉，並喚醒所有 worker 和等待中的提交者，呼叫方需持有鎖
+func (p *Pool) closeLocked() {
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.cond.Broadcast()
+	p.signalSpaceLocked()
+}
@@ -0,0 +1,120 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// blockPool 讓 Pool 唯一的 worker 阻塞，直到呼叫返回的函數，使之後提交的任務都留在佇列中
+func blockPool(t *testing.T, p *Pool) (release func()) {
+	t.Helper()
+	started := make(chan struct{})
+	gate := make(chan struct{})
+	if _, err := p.Submit(func() {
+		close(started)
+		<-gate
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	return func() { close(gate) }
+}
+
+func TestPoolPriority(t *testing.T) {
+	p := NewPool(1, 10)
+	defer p.Shutdown(context.Background())
+	release := blockPool(t, p)
+
+	var mu sync.Mutex
+	var order []string
+	submit := func(id string, priority int) *Future[TaskResult] {
+		f, err := p.SubmitTask(Task{ID: id, Priority: priority, Fn: func() {
+			mu.Lock()
+			order = append(order, id)
+			mu.Unlock()
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	futures := []*Future[TaskResult]{
+		submit("low", 0),
+		submit("high", 10),
+		submit("mid-1", 5),
+		submit("mid-2", 5),
+		submit("raised", 0),
+	}
+	if err := p.SetPriority("raised", 20); err != nil {
+		t.Fatalf("SetPriority: %v", err)
+	}
+	if err := p.SetPriority("missing", 1); !errors.Is(err, ErrTaskNotQueued) {
+		t.Errorf("SetPriority(missing) = %v, want ErrTaskNotQueued", err)
+	}
+
+	release()
+	for _, f := range futures {
+		if _, err := f.Await(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"raised", "high", "mid-1", "mid-2", "low"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	p := NewPool(1, 10)
+	defer p.Shutdown(context.Background())
+	release := blockPool(t, p)
+
+	ran := make(chan string, 3)
+	submit := func(id string) *Future[TaskResult] {
+		f, err := p.SubmitTask(Task{ID: id, Fn: func() { ran <- id }})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	keep := submit("keep")
+	dropA := submit("drop")
+	dropB := submit("drop")
+
+	if err := p.Cancel("drop"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if n := p.QueueLen(); n != 1 {
+		t.Errorf("QueueLen after Cancel = %d, want 1", n)
+	}
+	for _, f := range []*Future[TaskResult]{dropA, dropB} {
+		result, err := f.Await()
+		if !errors.Is(err, ErrTaskCancelled) || result.ID != "drop" {
+			t.Errorf("cancelled task = (%+v, %v), want ErrTaskCancelled", result, err)
+		}
+	}
+	if err := p.Cancel("drop"); !errors.Is(err, ErrTaskNotQueued) {
+		t.Errorf("second Cancel = %v, want ErrTaskNotQueued", err)
+	}
+
+	release()
+	if _, err := keep.Await(); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-ran; id != "keep" {
+		t.Errorf("ran %q, want keep", id)
+	}
+	if err := p.Cancel("keep"); !errors.Is(err, ErrTaskNotQueued) {
+		t.Errorf("Cancel after run = %v, want ErrTaskNotQueued", err)
+	}
+	select {
+	case id := <-ran:
+		t.Errorf("cancelled task %q ran", id)
+	default:
+	}
+}
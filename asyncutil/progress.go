@@ -0,0 +1,267 @@
+package asyncutil
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Progress 是 ProgressTracker 在某個時間點的進度
+type Progress struct {
+	Completed  float64       // 已完成的工作量，包含未完成項目回報的部分進度
+	Total      float64       // 總工作量
+	Elapsed    time.Duration // 從創建 ProgressTracker 到現在的時間
+	Throughput float64       // 每秒完成的工作量
+	ETA        time.Duration // 預計剩餘時間，無法估計時為 -1
+}
+
+// Percent 返回完成的百分比（0 到 100），總工作量為 0 時返回 0
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return p.Completed / p.Total * 100
+}
+
+// ProgressTracker 追蹤長時間執行的工作的進度，並每隔一段時間透過回呼函數和通道回報。
+// 搭配 WithProgress 選項時，asyncutil 的執行函數會自動增加總工作量並在每個項目完成時更新進度。
+type ProgressTracker struct {
+	mu        sync.Mutex
+	total     float64
+	completed float64
+	start     time.Time
+	clock     Clock
+	interval  time.Duration
+	fn        func(Progress)
+	updates   chan Progress
+	timer     Timer
+	stopped   bool
+
+	reportMu sync.Mutex // 保護 updates 的傳送和關閉
+	closed   bool       // updates 是否已關閉
+}
+
+// NewProgressTracker 創建一個 ProgressTracker，每隔 interval 以目前的進度呼叫 fn（可為 nil）並送到 Updates 通道。
+// interval 小於等於 0 時只在 Stop 時回報一次。fn 中可以呼叫 Stop，例如在完成時停止回報。可使用 WithClock 替換時間來源。
+func NewProgressTracker(interval time.Duration, fn func(Progress), opts ...Option) *ProgressTracker {
+	o := buildOptions(opts)
+	t := &ProgressTracker{
+		clock:    o.clockOrDefault(),
+		interval: interval,
+		fn:       fn,
+		updates:  make(chan Progress, 1),
+	}
+	t.start = t.clock.Now()
+
+	if interval > 0 {
+		t.mu.Lock()
+		t.timer = t.clock.AfterFunc(interval, t.tick)
+		t.mu.Unlock()
+	}
+	return t
+}
+
+// tick 回報進度並重新設定計時器
+func (t *ProgressTracker) tick() {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	t.timer = t.clock.AfterFunc(t.interval, t.tick)
+	t.mu.Unlock()
+
+	t.report(false)
+}
+
+// report 將目前的進度送到通道並呼叫回呼函數，通道中尚未被讀取的舊進度會被取代。
+// 通道關閉後不再回報；回呼函數在鎖外呼叫，因此可以在其中呼叫 Stop。
+func (t *ProgressTracker) report(final bool) {
+	p := t.Snapshot()
+
+	t.reportMu.Lock()
+	if t.closed {
+		t.reportMu.Unlock()
+		return
+	}
+	select {
+	case t.updates <- p:
+	default:
+		select {
+		case <-t.updates:
+		default:
+		}
+		t.updates <- p
+	}
+	if final {
+		t.closed = true
+		close(t.updates)
+	}
+	t.reportMu.Unlock()
+
+	if t.fn != nil {
+		t.fn(p)
+	}
+}
+
+// AddTotal 增加總工作量
+func (t *ProgressTracker) AddTotal(n float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.total += n
+	t.mu.Unlock()
+}
+
+// Advance 增加已完成的工作量
+func (t *ProgressTracker) Advance(n float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.completed += n
+	t.mu.Unlock()
+}
+
+// Snapshot 返回目前的進度
+func (t *ProgressTracker) Snapshot() Progress {
+	t.mu.Lock()
+	total, completed := t.total, t.completed
+	t.mu.Unlock()
+
+	p := Progress{
+		Completed: completed,
+		Total:     total,
+		Elapsed:   t.clock.Now().Sub(t.start),
+		ETA:       -1,
+	}
+	if p.Elapsed > 0 {
+		p.Throughput = completed / p.Elapsed.Seconds()
+	}
+	if p.Throughput > 0 && total >= completed {
+		p.ETA = time.Duration((total - completed) / p.Throughput * float64(time.Second))
+	}
+	return p
+}
+
+// Updates 返回接收定期進度的通道。通道只保留最新的進度，Stop 後會送出最終進度並關閉
+func (t *ProgressTracker) Updates() <-chan Progress {
+	return t.updates
+}
+
+// Stop 停止定期回報，並以最終進度呼叫回呼函數後關閉 Updates 通道。重複呼叫 Stop 是安全的
+func (t *ProgressTracker) Stop() {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.mu.Unlock()
+
+	t.report(true)
+}
+
+// Step 增加 1 個總工作量，並返回代表這一個項目的 ProgressStep，用於回報項目內部的進度
+func (t *ProgressTracker) Step() *ProgressStep {
+	if t == nil {
+		return nil
+	}
+	t.AddTotal(1)
+	return &ProgressStep{tracker: t}
+}
+
+// ProgressStep 代表 ProgressTracker 中的一個項目，可回報項目內部的部分進度。
+// 所有方法都可以在 nil 上呼叫，此時不做任何事。
+type ProgressStep struct {
+	mu       sync.Mutex
+	tracker  *ProgressTracker
+	fraction float64
+}
+
+// Update 將項目的完成比例設為 fraction（0 到 1），比例只會增加不會減少
+func (s *ProgressStep) Update(fraction float64) {
+	if s == nil {
+		return
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	s.mu.Lock()
+	delta := fraction - s.fraction
+	if delta <= 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.fraction = fraction
+	s.mu.Unlock()
+
+	s.tracker.Advance(delta)
+}
+
+// Done 將項目標記為完成
+func (s *ProgressStep) Done() {
+	s.Update(1)
+}
+
+// progressKey 是在 context 中保存 ProgressStep 的鍵
+type progressKey struct{}
+
+// ProgressFromContext 返回 ctx 中代表目前項目的 ProgressStep，沒有時返回 nil（仍可安全呼叫其方法）。
+// 設定 WithProgress 時，ParallelProcess、RunDAG、Pool、KeyedExecutor、Scope 和 DurableQueue.Process
+// 傳給任務的 context 都帶有此項目的 ProgressStep。
+func ProgressFromContext(ctx context.Context) *ProgressStep {
+	step, _ := ctx.Value(progressKey{}).(*ProgressStep)
+	return step
+}
+
+// WithProgress 讓 asyncutil 的執行函數將工作量加入 tracker，並在每個項目完成時更新進度
+func WithProgress(tracker *ProgressTracker) Option {
+	return func(o *options) {
+		o.progress = tracker
+	}
+}
+
+// reset 撤銷項目已回報的部分進度，用於項目需要重新執行時
+func (s *ProgressStep) reset() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	fraction := s.fraction
+	s.fraction = 0
+	s.mu.Unlock()
+
+	s.tracker.Advance(-fraction)
+}
+
+// progressStep 在設定了 WithProgress 時為一個項目建立 ProgressStep，並返回帶有它的 context
+func (o *options) progressStep(ctx context.Context) (context.Context, *ProgressStep) {
+	step := o.progress.Step()
+	return withProgressStep(ctx, step), step
+}
+
+// progressSteps 在設定了 WithProgress 時一次為 n 個項目建立 ProgressStep，未設定時返回的元素皆為 nil
+func (o *options) progressSteps(n int) []*ProgressStep {
+	steps := make([]*ProgressStep, n)
+	if o.progress == nil {
+		return steps
+	}
+	for i := range steps {
+		steps[i] = o.progress.Step()
+	}
+	return steps
+}
+
+// withProgressStep 返回帶有 step 的 context，step 為 nil 時直接返回 ctx
+func withProgressStep(ctx context.Context, step *ProgressStep) context.Context {
+	if step == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, step)
+}
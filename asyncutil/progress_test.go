@@ -0,0 +1,73 @@
+package asyncutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressTrackerStopFromCallback(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var tracker *ProgressTracker
+	tracker = NewProgressTracker(time.Second, func(p Progress) {
+		if p.Total > 0 && p.Completed >= p.Total {
+			tracker.Stop()
+		}
+	}, WithClock(clock))
+	tracker.AddTotal(1)
+	tracker.Advance(1)
+
+	done := make(chan struct{})
+	go func() {
+		clock.Advance(time.Second)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("calling Stop from the callback deadlocked")
+	}
+
+	var last Progress
+	for p := range tracker.Updates() {
+		last = p
+	}
+	if last.Completed != 1 || last.Total != 1 {
+		t.Errorf("last progress = %+v, want 1/1", last)
+	}
+}
+
+func TestProgressTrackerConcurrentStop(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		tracker := NewProgressTracker(time.Microsecond, nil)
+		done := make(chan struct{})
+		go func() {
+			for range tracker.Updates() {
+			}
+			close(done)
+		}()
+		time.Sleep(10 * time.Microsecond)
+		tracker.Stop()
+		tracker.Stop()
+		<-done
+	}
+}
+
+func TestProgressSnapshot(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	tracker := NewProgressTracker(0, nil, WithClock(clock))
+	if p := tracker.Snapshot(); p.ETA != -1 {
+		t.Errorf("ETA without progress = %v, want -1", p.ETA)
+	}
+
+	step := tracker.Step()
+	tracker.Step()
+	step.Update(0.5)
+	step.Update(0.25) // 比例不會減少
+	clock.Advance(time.Second)
+
+	p := tracker.Snapshot()
+	if p.Completed != 0.5 || p.Total != 2 || p.Throughput != 0.5 || p.ETA != 3*time.Second {
+		t.Errorf("Snapshot = %+v", p)
+	}
+	tracker.Stop()
+}
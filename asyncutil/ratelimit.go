@@ -0,0 +1,203 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimitExceeded 表示在 ctx 的期限內無法取得令牌
+var ErrRateLimitExceeded = errors.New("asyncutil: rate limit wait would exceed context deadline")
+
+// RateLimiter 是一個令牌桶限流器，每秒產生 rate 個令牌，最多累積 burst 個
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64   // 每秒產生的令牌數量
+	burst  int       // 令牌桶容量
+	tokens float64   // 目前的令牌數量，可能因預約而為負數
+	last   time.Time // 上次更新令牌數量的時間
+}
+
+// NewRateLimiter 創建一個每秒產生 ratePerSecond 個令牌、最多累積 burst 個令牌的限流器，令牌桶初始為滿
+func NewRateLimiter(ratePerSecond float64, burst int) *RateLimiter {
+	if ratePerSecond <= 0 {
+		panic("NewRateLimiter: ratePerSecond must be positive")
+	}
+	if burst < 1 {
+		panic("NewRateLimiter: burst must be at least 1")
+	}
+
+	return &RateLimiter{
+		rate:   ratePerSecond,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// advanceLocked 依照經過的時間補充令牌，呼叫方需持有鎖
+func (l *RateLimiter) advanceLocked(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(float64(l.burst), l.tokens+elapsed.Seconds()*l.rate)
+		l.last = now
+	}
+}
+
+// Allow 若目前有可用的令牌則取用一個並返回 true，否則返回 false 且不取用令牌
+func (l *RateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advanceLocked(time.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Reservation 表示預約的一個令牌，需等待 Delay() 後才能執行動作
+type Reservation struct {
+	limiter   *RateLimiter
+	timeToAct time.Time
+	canceled  bool
+}
+
+// Reserve 預約一個令牌並返回 Reservation，令牌不足時會預支未來的令牌
+func (l *RateLimiter) Reserve() *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.advanceLocked(now)
+	l.tokens--
+
+	r := &Reservation{limiter: l, timeToAct: now}
+	if l.tokens < 0 {
+		r.timeToAct = now.Add(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+	}
+	return r
+}
+
+// Delay 返回距離可以執行動作還需要等待的時間
+func (r *Reservation) Delay() time.Duration {
+	if d := time.Until(r.timeToAct); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel 取消尚未到期的預約，並歸還令牌
+func (r *Reservation) Cancel() {
+	l := r.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.canceled || !time.Now().Before(r.timeToAct) {
+		return
+	}
+	r.canceled = true
+	l.tokens = math.Min(float64(l.burst), l.tokens+1)
+}
+
+// Wait 阻塞直到取得一個令牌。若 ctx 被取消，或預計的等待時間會超過 ctx 的期限，返回錯誤並歸還令牌。
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r := l.Reserve()
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		r.Cancel()
+		return ErrRateLimitExceeded
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// KeyedRateLimiter 為每個鍵（例如租戶或主機）維護獨立的令牌桶
+type KeyedRateLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    int
+	limiters map[string]*RateLimiter
+}
+
+// NewKeyedRateLimiter 創建一個 KeyedRateLimiter，每個鍵的令牌桶都使用相同的 ratePerSecond 和 burst
+func NewKeyedRateLimiter(ratePerSecond float64, burst int) *KeyedRateLimiter {
+	if ratePerSecond <= 0 {
+		panic("NewKeyedRateLimiter: ratePerSecond must be positive")
+	}
+	if burst < 1 {
+		panic("NewKeyedRateLimiter: burst must be at least 1")
+	}
+
+	return &KeyedRateLimiter{
+		rate:     ratePerSecond,
+		burst:    burst,
+		limiters: make(map[string]*RateLimiter),
+	}
+}
+
+// Limiter 返回指定鍵的令牌桶，不存在時自動創建
+func (k *KeyedRateLimiter) Limiter(key string) *RateLimiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	l, ok := k.limiters[key]
+	if !ok {
+		l = NewRateLimiter(k.rate, k.burst)
+		k.limiters[key] = l
+	}
+	return l
+}
+
+// Allow 對指定鍵的令牌桶呼叫 Allow
+func (k *KeyedRateLimiter) Allow(key string) bool {
+	return k.Limiter(key).Allow()
+}
+
+// Wait 對指定鍵的令牌桶呼叫 Wait
+func (k *KeyedRateLimiter) Wait(ctx context.Context, key string) error {
+	return k.Limiter(key).Wait(ctx)
+}
+
+// Remove 移除指定鍵的令牌桶，用於釋放不再使用的鍵
+func (k *KeyedRateLimiter) Remove(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.limiters, key)
+}
+
+// waitRateLimit 依照設定的限流器等待任務可以執行
+func (o *options) waitRateLimit(ctx context.Context, task Task) error {
+	if o.rateLimiter != nil {
+		if err := o.rateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if o.keyedLimiter != nil {
+		key := task.ID
+		if o.rateLimitKey != nil {
+			key = o.rateLimitKey(task)
+		}
+		if err := o.keyedLimiter.Wait(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
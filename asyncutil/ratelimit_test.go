@@ -0,0 +1,158 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	l := NewRateLimiter(20, 2)
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("Allow should succeed while burst tokens remain")
+	}
+	if l.Allow() {
+		t.Fatal("Allow should fail once the bucket is empty")
+	}
+	time.Sleep(60 * time.Millisecond) // 補充一個令牌
+	if !l.Allow() {
+		t.Error("Allow should succeed after a token is refilled")
+	}
+}
+
+func TestRateLimiterReserveAndCancel(t *testing.T) {
+	l := NewRateLimiter(10, 1)
+	if d := l.Reserve().Delay(); d != 0 {
+		t.Errorf("first Reserve delay = %v, want 0", d)
+	}
+
+	r := l.Reserve()
+	if d := r.Delay(); d < 50*time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("second Reserve delay = %v, want about 100ms", d)
+	}
+	third := l.Reserve()
+	if d := third.Delay(); d < 150*time.Millisecond {
+		t.Errorf("third Reserve delay = %v, want about 200ms", d)
+	}
+
+	// 取消尚未到期的預約會歸還令牌，之後的預約只需等待一個令牌
+	third.Cancel()
+	r.Cancel()
+	third.Cancel() // 重複取消不會再次歸還
+	if d := l.Reserve().Delay(); d > 100*time.Millisecond {
+		t.Errorf("Reserve delay after Cancel = %v, want at most 100ms", d)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(10, 1)
+	ctx := context.Background()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("Wait with a full bucket: %v", err)
+	}
+
+	start := time.Now()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("Wait returned after %v, want about 100ms", d)
+	}
+
+	// 等待時間超過期限時立即返回，且不消耗令牌
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if err := l.Wait(short); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("Wait past deadline = %v, want ErrRateLimitExceeded", err)
+	}
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Errorf("Wait past deadline returned after %v, want immediately", d)
+	}
+	time.Sleep(110 * time.Millisecond)
+	if !l.Allow() {
+		t.Error("the token of a failed Wait should be returned")
+	}
+
+	cancelled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	if err := l.Wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait with cancelled ctx = %v, want context.Canceled", err)
+	}
+}
+
+func TestPoolKeyedRateLimiter(t *testing.T) {
+	limiter := NewKeyedRateLimiter(10, 1)
+	p := NewPool(4, 10, WithKeyedRateLimiter(limiter, nil))
+	defer p.Shutdown(context.Background())
+
+	start := time.Now()
+	var futures []*Future[TaskResult]
+	for _, id := range []string{"A", "A", "B"} {
+		f, err := p.SubmitTask(Task{ID: id, Fn: func() time.Duration { return time.Since(start) }})
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures = append(futures, f)
+	}
+
+	var elapsed []time.Duration
+	for _, f := range futures {
+		result, err := f.Await()
+		if err != nil {
+			t.Fatal(err)
+		}
+		elapsed = append(elapsed, result.Results[0].(time.Duration))
+	}
+	if a := max(elapsed[0], elapsed[1]); a < 50*time.Millisecond {
+		t.Errorf("second A task ran after %v, want it to wait for A's bucket", a)
+	}
+	if b := elapsed[2]; b > 50*time.Millisecond {
+		t.Errorf("B task ran after %v, want it not to wait", b)
+	}
+}
+
+func TestParallelProcessKeyedRateLimitIsolation(t *testing.T) {
+	limiter := NewKeyedRateLimiter(10, 1)
+	key := func(task Task) string { return task.ID[:1] }
+
+	var mu sync.Mutex
+	finished := make(map[string]time.Duration)
+	start := time.Now()
+	record := func(id string) func() {
+		return func() {
+			mu.Lock()
+			finished[id] = time.Since(start)
+			mu.Unlock()
+		}
+	}
+
+	// A 的令牌桶在第一個任務後就耗盡，其餘的 A 任務需等待約 300ms
+	var tasks []Task
+	for _, id := range []string{"A1", "A2", "A3", "A4", "B1"} {
+		tasks = append(tasks, Task{ID: id, Fn: record(id)})
+	}
+	results := ParallelProcess(tasks, WithKeyedRateLimiter(limiter, key))
+	for _, r := range results {
+		if r.Err != nil {
+			t.Fatalf("task %s: %v", r.ID, r.Err)
+		}
+	}
+
+	if d := finished["B1"]; d > 100*time.Millisecond {
+		t.Errorf("B1 finished after %v, want it not to wait for A's bucket", d)
+	}
+
+	// A 任務取得令牌的順序不固定，最後一個至少要等待三個令牌的時間
+	var last time.Duration
+	for _, id := range []string{"A1", "A2", "A3", "A4"} {
+		if finished[id] > last {
+			last = finished[id]
+		}
+	}
+	if last < 250*time.Millisecond {
+		t.Errorf("last A task finished after %v, want at least 250ms", last)
+	}
+}
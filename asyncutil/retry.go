@@ -0,0 +1,138 @@
+package asyncutil
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffStrategy 定義枚舉類型，用於指定重試之間的等待時間如何增長
+type BackoffStrategy int
+
+const (
+	BackoffConstant    BackoffStrategy = iota // 每次等待 InitialDelay
+	BackoffLinear                             // 第 n 次重試等待 InitialDelay * n
+	BackoffExponential                        // 第 n 次重試等待 InitialDelay * Multiplier^(n-1)
+)
+
+// RetryPolicy 定義失敗時的重試策略
+type RetryPolicy struct {
+	MaxAttempts    int              // 最多嘗試次數（包含第一次），小於 1 時視為 1
+	Backoff        BackoffStrategy  // 等待時間的增長方式
+	InitialDelay   time.Duration    // 第一次重試前的等待時間
+	MaxDelay       time.Duration    // 單次等待時間的上限，0 表示不限制
+	Multiplier     float64          // BackoffExponential 的倍數，小於等於 1 時使用 2
+	Jitter         float64          // 隨機抖動比例（0 到 1），等待時間會在 ±Jitter 的範圍內隨機變動
+	MaxElapsedTime time.Duration    // 從第一次嘗試開始計算的總時間上限，0 表示不限制
+	Retryable      func(error) bool // 判斷錯誤是否可以重試，nil 表示所有錯誤都可重試
+}
+
+// Delay 返回第 retry 次重試（從 1 開始）前應等待的時間，不包含隨機抖動
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	var d float64
+	switch p.Backoff {
+	case BackoffLinear:
+		d = float64(p.InitialDelay) * float64(retry)
+	case BackoffExponential:
+		multiplier := p.Multiplier
+		if multiplier <= 1 {
+			multiplier = 2
+		}
+		d = float64(p.InitialDelay) * math.Pow(multiplier, float64(retry-1))
+	default:
+		d = float64(p.InitialDelay)
+	}
+
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	if d > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+// jitter 依照 Jitter 比例對等待時間加上隨機抖動
+func (p RetryPolicy) jitter(d time.Duration) time.Duration {
+	if p.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	ratio := math.Min(p.Jitter, 1)
+	return time.Duration(float64(d) * (1 + ratio*(2*rand.Float64()-1)))
+}
+
+// RetryError 表示重試後仍然失敗，包含嘗試次數和每次嘗試的錯誤
+type RetryError struct {
+	Attempts int     // 實際嘗試的次數
+	Errors   []error // 每次嘗試的錯誤；若在等待重試時 ctx 結束，ctx 的錯誤會附加在最後
+}
+
+// Error 實作 error 介面
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("asyncutil: failed after %d attempts: %v", e.Attempts, e.Errors[len(e.Errors)-1])
+}
+
+// Unwrap 返回所有錯誤，支援 errors.Is 和 errors.As
+func (e *RetryError) Unwrap() []error {
+	return e.Errors
+}
+
+// Retry 依照 policy 執行 fn，失敗時等待後重試，直到成功、錯誤不可重試、達到次數或時間上限，或 ctx 結束。
+// 失敗時返回 *RetryError，其中記錄了嘗試次數和每次嘗試的錯誤；fn 中的 panic 會以 *PanicError 記錄且不會重試。
+func Retry[T any](ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	attempts, errs, err := retryLoop(ctx, policy, func(ctx context.Context) error {
+		var err error
+		value, err = recoverCall(func() (T, error) {
+			return fn(ctx)
+		})
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, &RetryError{Attempts: attempts, Errors: errs}
+	}
+	return value, nil
+}
+
+// retryLoop 依照 policy 重複呼叫 attempt，返回嘗試次數、每次嘗試的錯誤和最後的錯誤
+func retryLoop(ctx context.Context, policy RetryPolicy, attempt func(ctx context.Context) error) (int, []error, error) {
+	start := time.Now()
+	var errs []error
+
+	for n := 1; ; n++ {
+		err := attempt(ctx)
+		if err == nil {
+			return n, errs, nil
+		}
+		errs = append(errs, err)
+
+		if n >= policy.MaxAttempts || !policy.shouldRetry(err) {
+			return n, errs, err
+		}
+
+		delay := policy.jitter(policy.Delay(n))
+		if policy.MaxElapsedTime > 0 && time.Since(start)+delay > policy.MaxElapsedTime {
+			return n, errs, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			errs = append(errs, ctx.Err())
+			return n, errs, ctx.Err()
+		}
+	}
+}
+
+// shouldRetry 判斷錯誤是否可以重試，panic 永遠不會重試
+func (p RetryPolicy) shouldRetry(err error) bool {
+	if _, isPanic := err.(*PanicError); isPanic {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
@@ -0,0 +1,248 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrJobNotFound 表示 Scheduler 中沒有指定 ID 的工作
+	ErrJobNotFound = errors.New("asyncutil: scheduled job not found")
+	// ErrSchedulerRunning 表示 Scheduler 已經在執行中
+	ErrSchedulerRunning = errors.New("asyncutil: scheduler is already running")
+)
+
+// OverlapPolicy 定義枚舉類型，指定工作的上一次執行尚未結束時如何處理新的觸發
+type OverlapPolicy int
+
+const (
+	OverlapSkip  OverlapPolicy = iota // 略過這次觸發
+	OverlapQueue                      // 等上一次執行結束後再執行
+	OverlapAllow                      // 允許同時執行
+)
+
+// ScheduledJob 描述 Scheduler 中的一個工作
+type ScheduledJob struct {
+	ID      string    // 工作的標識符，即 Task.ID
+	NextRun time.Time // 下一次執行的時間，Scheduler 尚未執行時為預計時間
+	Running int       // 目前正在執行的次數
+}
+
+// scheduledJob 是 Scheduler 內部保存的工作
+type scheduledJob struct {
+	task    Task
+	next    func(after time.Time) time.Time // 計算下一次執行時間
+	policy  OverlapPolicy
+	nextRun time.Time
+	timer   Timer
+	running int // 正在執行的次數
+	queued  int // OverlapQueue 下等待執行的次數
+}
+
+// Scheduler 依照 cron 表達式或固定間隔定期執行 Task
+type Scheduler struct {
+	mu       sync.Mutex
+	clock    Clock
+	location *time.Location
+	onResult func(TaskResult)
+	jobs     map[string]*scheduledJob
+
+	ctx context.Context // Run 期間的 context，nil 表示 Scheduler 尚未執行
+	wg  sync.WaitGroup  // 正在執行的工作
+}
+
+// NewScheduler 創建一個 Scheduler。可使用 WithLocation 指定 cron 表達式的時區（預設為 time.Local）、
+// WithClock 替換時間來源、WithResultHandler 接收每次執行的結果
+func NewScheduler(opts ...Option) *Scheduler {
+	o := buildOptions(opts)
+	loc := o.location
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Scheduler{
+		clock:    o.clockOrDefault(),
+		location: loc,
+		onResult: o.resultHandler,
+		jobs:     make(map[string]*scheduledJob),
+	}
+}
+
+// AddCron 以 Task.ID 為標識符加入一個依照 cron 表達式執行的工作
+func (s *Scheduler) AddCron(spec string, task Task, policy OverlapPolicy) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	return s.add(task, policy, func(after time.Time) time.Time {
+		return schedule.Next(after.In(s.location))
+	})
+}
+
+// AddInterval 以 Task.ID 為標識符加入一個每隔 every 執行一次的工作，第一次在加入（或 Run 開始）後 every 執行
+func (s *Scheduler) AddInterval(every time.Duration, task Task, policy OverlapPolicy) error {
+	if every <= 0 {
+		return fmt.Errorf("asyncutil: invalid interval %v for job %q", every, task.ID)
+	}
+	return s.add(task, policy, func(after time.Time) time.Time {
+		return after.Add(every)
+	})
+}
+
+// add 加入工作，若 Scheduler 正在執行則立即開始計時
+func (s *Scheduler) add(task Task, policy OverlapPolicy, next func(time.Time) time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[task.ID]; exists {
+		return fmt.Errorf("%w: %q", ErrDuplicateTaskID, task.ID)
+	}
+
+	job := &scheduledJob{task: task, next: next, policy: policy}
+	job.nextRun = next(s.clock.Now())
+	s.jobs[task.ID] = job
+	if s.ctx != nil {
+		s.armLocked(job)
+	}
+	return nil
+}
+
+// Remove 移除指定 ID 的工作，正在執行的工作不受影響
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrJobNotFound, id)
+	}
+	if job.timer != nil {
+		job.timer.Stop()
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+// NextRun 返回指定 ID 的工作下一次執行的時間
+func (s *Scheduler) NextRun(id string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrJobNotFound, id)
+	}
+	return job.nextRun, nil
+}
+
+// Jobs 返回所有工作，依照下一次執行的時間排序
+func (s *Scheduler) Jobs() []ScheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]ScheduledJob, 0, len(s.jobs))
+	for id, job := range s.jobs {
+		jobs = append(jobs, ScheduledJob{ID: id, NextRun: job.nextRun, Running: job.running})
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].NextRun.Before(jobs[j].NextRun)
+	})
+	return jobs
+}
+
+// Run 開始依照排程執行所有工作，並阻塞直到 ctx 結束。
+// 工作函數的第一個參數若是 context.Context 會收到 ctx；ctx 結束後不再觸發新的執行，並等待正在執行的工作結束後返回 ctx 的錯誤。
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return ErrSchedulerRunning
+	}
+	s.ctx = ctx
+	now := s.clock.Now()
+	for _, job := range s.jobs {
+		job.nextRun = job.next(now)
+		s.armLocked(job)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		if job.timer != nil {
+			job.timer.Stop()
+			job.timer = nil
+		}
+		job.queued = 0
+	}
+	s.ctx = nil
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return ctx.Err()
+}
+
+// armLocked 為工作設定下一次執行的計時器，呼叫方需持有鎖
+func (s *Scheduler) armLocked(job *scheduledJob) {
+	if job.nextRun.IsZero() {
+		return
+	}
+	at := job.nextRun
+	job.timer = s.clock.AfterFunc(at.Sub(s.clock.Now()), func() {
+		s.fire(job, at)
+	})
+}
+
+// fire 在排定的時間觸發工作，並依照 OverlapPolicy 決定是否執行
+func (s *Scheduler) fire(job *scheduledJob, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 工作已被移除、Scheduler 已停止或計時器已過期
+	if s.ctx == nil || s.jobs[job.task.ID] != job || !job.nextRun.Equal(at) {
+		return
+	}
+
+	// 以排定的時間計算下一次執行，避免累積誤差；若已落後則跳過錯過的時間
+	now := s.clock.Now()
+	next := job.next(at)
+	for !next.IsZero() && !next.After(now) {
+		next = job.next(next)
+	}
+	job.nextRun = next
+	s.armLocked(job)
+
+	switch {
+	case job.running == 0 || job.policy == OverlapAllow:
+		s.startLocked(job)
+	case job.policy == OverlapQueue:
+		job.queued++
+	}
+}
+
+// startLocked 在新的 goroutine 中執行工作，呼叫方需持有鎖
+func (s *Scheduler) startLocked(job *scheduledJob) {
+	ctx := s.ctx
+	job.running++
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		result := runTask(ctx, job.task)
+		if s.onResult != nil {
+			s.onResult(result)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		job.running--
+		if job.queued > 0 && s.ctx != nil {
+			job.queued--
+			s.startLocked(job)
+		}
+	}()
+}
@@ -0,0 +1,113 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Scope 管理一組屬於同一個工作的 goroutine：任何一個返回錯誤或發生 panic 時取消其餘的 goroutine，
+// Wait 會等待所有 goroutine 結束後才返回，確保它們不會比呼叫方活得更久。
+type Scope struct {
+	ctx        context.Context
+	cancel     context.CancelCauseFunc
+	limit      chan struct{} // 限制同時執行的 goroutine 數量，nil 表示不限制
+	joinErrors bool
+	progress   *ProgressTracker
+	wg         sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error // 依發生順序記錄的錯誤
+}
+
+// NewScope 創建一個 Scope，並返回從 ctx 衍生的 context。
+// 返回的 context 會在第一個 goroutine 失敗或 Wait 返回時取消，context.Cause 會返回第一個錯誤。可用的選項：
+//   - WithConcurrency(n)：同時執行的 goroutine 數量上限，n <= 0 表示不限制。
+//   - WithJoinErrors()：Wait 以 errors.Join 返回所有錯誤，而不是只返回第一個錯誤。
+//   - WithProgress(tracker)：每個透過 Go 啟動的 goroutine 計為一個項目。
+func NewScope(ctx context.Context, opts ...Option) (*Scope, context.Context) {
+	o := buildOptions(opts)
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	s := &Scope{
+		ctx:        ctx,
+		cancel:     cancel,
+		joinErrors: o.joinErrors,
+		progress:   o.progress,
+	}
+	if o.concurrency > 0 {
+		s.limit = make(chan struct{}, o.concurrency)
+	}
+	return s, ctx
+}
+
+// Go 在新的 goroutine 中執行 fn，並傳入 NewScope 返回的 context。
+// 同時執行的 goroutine 數量已達到上限時，Go 會阻塞直到有 goroutine 結束。
+func (s *Scope) Go(fn func(ctx context.Context) error) {
+	if s.limit != nil {
+		s.limit <- struct{}{}
+	}
+	s.start(fn)
+}
+
+// TryGo 與 Go 相同，但同時執行的 goroutine 數量已達到上限時不阻塞，直接返回 false
+func (s *Scope) TryGo(fn func(ctx context.Context) error) bool {
+	if s.limit != nil {
+		select {
+		case s.limit <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	s.start(fn)
+	return true
+}
+
+// start 啟動 goroutine 執行 fn，fn 的 panic 會轉換為 *PanicError 並視為錯誤
+func (s *Scope) start(fn func(ctx context.Context) error) {
+	step := s.progress.Step()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if s.limit != nil {
+			defer func() { <-s.limit }()
+		}
+		defer step.Done()
+
+		_, err := recoverCall(func() (struct{}, error) {
+			return struct{}{}, fn(withProgressStep(s.ctx, step))
+		})
+		if err != nil {
+			s.fail(err)
+		}
+	}()
+}
+
+// fail 記錄錯誤，並在第一個錯誤發生時取消其餘的 goroutine
+func (s *Scope) fail(err error) {
+	s.mu.Lock()
+	s.errs = append(s.errs, err)
+	first := len(s.errs) == 1
+	s.mu.Unlock()
+
+	if first {
+		s.cancel(err)
+	}
+}
+
+// Wait 等待所有透過 Go 啟動的 goroutine 結束，然後取消 Scope 的 context。
+// 返回第一個錯誤（設定 WithJoinErrors 時返回所有錯誤），沒有錯誤時返回 nil；panic 會以 *PanicError 返回。
+func (s *Scope) Wait() error {
+	s.wg.Wait()
+	s.cancel(nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.errs) == 0 {
+		return nil
+	}
+	if s.joinErrors {
+		return errors.Join(s.errs...)
+	}
+	return s.errs[0]
+}
@@ -0,0 +1,120 @@
+package asyncutil
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrWeightTooLarge 表示要求的權重超過 Semaphore 的容量，永遠無法取得
+var ErrWeightTooLarge = errors.New("asyncutil: weight exceeds semaphore capacity")
+
+// Semaphore 是帶權重的信號量，用於限制同時使用的資源總量（例如記憶體）。
+// 等待者依照呼叫 Acquire 的順序取得資源，較大的請求不會被後來的較小請求插隊而餓死。
+type Semaphore struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters list.List // 依先後順序排列的 *semaphoreWaiter
+}
+
+// semaphoreWaiter 表示一個等待中的 Acquire 呼叫
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{} // 取得資源時關閉
+}
+
+// NewSemaphore 創建一個總權重為 n 的 Semaphore
+func NewSemaphore(n int64) *Semaphore {
+	if n < 1 {
+		panic("NewSemaphore: n must be at least 1")
+	}
+	return &Semaphore{size: n}
+}
+
+// Acquire 取得權重 n，阻塞直到資源足夠且排在前面的等待者都已取得資源。
+// ctx 先被取消時返回 ctx 的錯誤且不取得任何資源；n 超過容量時返回 ErrWeightTooLarge。
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	if n < 0 {
+		panic("Semaphore.Acquire: n must not be negative")
+	}
+
+	s.mu.Lock()
+	if n > s.size {
+		s.mu.Unlock()
+		return ErrWeightTooLarge
+	}
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	w := &semaphoreWaiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-w.ready:
+			// 取消的同時已取得資源，歸還後再返回錯誤
+			s.cur -= n
+			s.notifyWaitersLocked()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// 排在最前面的等待者離開時，後面的等待者可能已經可以取得資源
+			if isFront && s.size > s.cur {
+				s.notifyWaitersLocked()
+			}
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// TryAcquire 在不阻塞的情況下嘗試取得權重 n，成功時返回 true
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release 歸還權重 n，歸還的權重超過已取得的權重時會 panic
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("Semaphore.Release: released more than held")
+	}
+	s.notifyWaitersLocked()
+}
+
+// notifyWaitersLocked 依序喚醒資源足夠的等待者，遇到資源不足的等待者即停止以維持先後順序，呼叫方需持有鎖
+func (s *Semaphore) notifyWaitersLocked() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*semaphoreWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,170 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acquireAsync 在新的 goroutine 中呼叫 Acquire，結果從返回的通道輸出
+func acquireAsync(ctx context.Context, s *Semaphore, n int64) <-chan error {
+	ch := make(chan error, 1)
+	go func() { ch <- s.Acquire(ctx, n) }()
+	return ch
+}
+
+// expectBlocked 確認 Acquire 尚未返回
+func expectBlocked(t *testing.T, name string, ch <-chan error) {
+	t.Helper()
+	select {
+	case err := <-ch:
+		t.Fatalf("%s returned early: %v", name, err)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// expectResult 確認 Acquire 返回 want
+func expectResult(t *testing.T, name string, ch <-chan error, want error) {
+	t.Helper()
+	select {
+	case err := <-ch:
+		if !errors.Is(err, want) {
+			t.Fatalf("%s = %v, want %v", name, err, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not return", name)
+	}
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	s := NewSemaphore(10)
+	ctx := context.Background()
+	if err := s.Acquire(ctx, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	big := acquireAsync(ctx, s, 5)
+	expectBlocked(t, "big", big)
+
+	// 剩餘的 2 足夠小的請求，但它排在大的請求之後
+	if s.TryAcquire(1) {
+		t.Fatal("TryAcquire jumped ahead of a waiter")
+	}
+	small := acquireAsync(ctx, s, 1)
+	expectBlocked(t, "small", small)
+
+	s.Release(8)
+	expectResult(t, "big", big, nil)
+	expectResult(t, "small", small, nil)
+
+	// 目前已使用 6
+	if !s.TryAcquire(4) || s.TryAcquire(1) {
+		t.Error("semaphore should have exactly 4 left")
+	}
+}
+
+func TestSemaphoreCancelFrontWaiter(t *testing.T) {
+	s := NewSemaphore(10)
+	if err := s.Acquire(context.Background(), 8); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	big := acquireAsync(ctx, s, 5)
+	expectBlocked(t, "big", big)
+	small := acquireAsync(context.Background(), s, 2)
+	expectBlocked(t, "small", small)
+
+	// 最前面的等待者離開後，後面的等待者立即取得剩餘的資源
+	cancel()
+	expectResult(t, "big", big, context.Canceled)
+	expectResult(t, "small", small, nil)
+	if s.TryAcquire(1) {
+		t.Error("semaphore should be full")
+	}
+}
+
+func TestSemaphoreCancelWhileGranted(t *testing.T) {
+	s := NewSemaphore(4)
+	if err := s.Acquire(context.Background(), 4); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiter := acquireAsync(ctx, s, 3)
+	expectBlocked(t, "waiter", waiter)
+
+	// 持有鎖時取消，讓等待者在取得鎖之前停在 ctx.Done 分支；再於同一個鎖內分配資源給它
+	s.mu.Lock()
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	s.cur -= 4
+	s.notifyWaitersLocked()
+	s.mu.Unlock()
+
+	expectResult(t, "waiter", waiter, context.Canceled)
+	// 已分配的權重必須歸還
+	if !s.TryAcquire(4) {
+		t.Error("weight granted to a cancelled waiter was not returned")
+	}
+}
+
+func TestSemaphoreWeightTooLarge(t *testing.T) {
+	s := NewSemaphore(3)
+	if err := s.Acquire(context.Background(), 4); !errors.Is(err, ErrWeightTooLarge) {
+		t.Errorf("Acquire(4) = %v, want ErrWeightTooLarge", err)
+	}
+	if !s.TryAcquire(3) {
+		t.Error("a rejected Acquire must not take any weight")
+	}
+}
+
+func TestPoolSemaphoreWeight(t *testing.T) {
+	sem := NewSemaphore(3)
+	p := NewPool(4, 10, WithSemaphore(sem))
+	defer p.Shutdown(context.Background())
+
+	var mu sync.Mutex
+	var cur, peak int64
+	task := func(weight int64) Task {
+		return Task{Weight: weight, Fn: func() {
+			mu.Lock()
+			cur += weight
+			if cur > peak {
+				peak = cur
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			cur -= weight
+			mu.Unlock()
+		}}
+	}
+
+	var futures []*Future[TaskResult]
+	for _, w := range []int64{2, 2, 1, 3, 0, 1, 2} {
+		f, err := p.SubmitTask(task(w))
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures = append(futures, f)
+	}
+	for _, f := range futures {
+		if _, err := f.Await(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if peak > 3 {
+		t.Errorf("peak weight in use = %d, want at most 3", peak)
+	}
+
+	f, err := p.SubmitTask(task(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Await(); !errors.Is(err, ErrWeightTooLarge) {
+		t.Errorf("task heavier than the semaphore = %v, want ErrWeightTooLarge", err)
+	}
+}
@@ -0,0 +1,120 @@
+package asyncutil
+
+import (
+	"sync"
+	"time"
+)
+
+// Group 讓相同鍵的並發呼叫共用同一次執行，所有呼叫方都會收到相同的結果。
+// 零值的 Group 可以直接使用，NewGroup 可額外設定結果快取。
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall // 執行中的呼叫
+	cache map[string]*flightCall // 已完成且仍在快取期限內的呼叫
+	ttl   time.Duration          // 成功結果的快取時間，0 表示不快取
+}
+
+// flightCall 表示一次執行中或已完成的呼叫
+type flightCall struct {
+	done    chan struct{}
+	val     interface{}
+	err     error
+	dups    int       // 共用此次執行的其他呼叫方數量
+	expires time.Time // 快取到期時間
+}
+
+// GroupResult 是 DoChan 輸出的結果
+type GroupResult struct {
+	Val    interface{} // fn 返回的值
+	Err    error       // fn 返回的錯誤
+	Shared bool        // 結果是否與其他呼叫方共用或來自快取
+}
+
+// NewGroup 創建一個新的 Group，可使用 WithResultTTL 在執行成功後短暫快取結果
+func NewGroup(opts ...Option) *Group {
+	return &Group{ttl: buildOptions(opts).resultTTL}
+}
+
+// Do 執行 fn 並返回結果。若相同 key 已有執行中的呼叫，則等待並共用該次的結果，不會再次執行 fn。
+// shared 表示結果是否與其他呼叫方共用或來自快取；fn 中的 panic 會以 *PanicError 返回給所有呼叫方。
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	c, shared := g.join(key, fn)
+	<-c.done
+	return c.val, c.err, shared
+}
+
+// DoChan 與 Do 相同，但不阻塞，結果會從返回的通道輸出
+func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan GroupResult {
+	ch := make(chan GroupResult, 1)
+	c, shared := g.join(key, fn)
+	go func() {
+		<-c.done
+		ch <- GroupResult{Val: c.val, Err: c.err, Shared: shared}
+	}()
+	return ch
+}
+
+// join 返回 key 對應的呼叫，若沒有執行中或快取中的呼叫則啟動新的執行
+func (g *Group) join(key string, fn func() (interface{}, error)) (*flightCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, ok := g.cache[key]; ok {
+		if time.Now().Before(c.expires) {
+			return c, true
+		}
+		delete(g.cache, key)
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		return c, true
+	}
+
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	go g.run(key, c, fn)
+	return c, false
+}
+
+// run 執行 fn 並將結果通知所有等待中的呼叫方
+func (g *Group) run(key string, c *flightCall, fn func() (interface{}, error)) {
+	c.val, c.err = recoverCall(fn)
+
+	g.mu.Lock()
+	// 若在執行期間呼叫了 Forget，對應的呼叫可能已被移除或替換
+	if g.calls[key] == c {
+		delete(g.calls, key)
+		if g.ttl > 0 && c.err == nil {
+			if g.cache == nil {
+				g.cache = make(map[string]*flightCall)
+			}
+			c.expires = time.Now().Add(g.ttl)
+			g.cache[key] = c
+			// 到期後移除快取，避免不再被請求的鍵一直佔用記憶體
+			time.AfterFunc(g.ttl, func() { g.evict(key, c) })
+		}
+	}
+	g.mu.Unlock()
+
+	close(c.done)
+}
+
+// evict 在 key 的快取仍是 c 時將其移除
+func (g *Group) evict(key string, c *flightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cache[key] == c {
+		delete(g.cache, key)
+	}
+}
+
+// Forget 讓 Group 忘記 key 對應的執行中呼叫和快取結果，之後相同 key 的呼叫會重新執行 fn
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+	delete(g.cache, key)
+}
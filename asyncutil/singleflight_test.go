@@ -0,0 +1,67 @@
+package asyncutil
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDedupStopsWaitingOnCallerCancel(t *testing.T) {
+	g := NewGroup()
+	release := make(chan struct{})
+	defer close(release)
+	slow := Task{ID: "shared", Fn: func() int { <-release; return 1 }}
+
+	go ParallelProcess([]Task{slow}, WithDedup(g))
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := ParallelProcessCtx(ctx, []Task{slow}, WithDedup(g))
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("ParallelProcessCtx returned after %v, want shortly after the deadline", d)
+	}
+	if !errors.Is(results[0].Err, context.DeadlineExceeded) {
+		t.Errorf("Err = %v, want context.DeadlineExceeded", results[0].Err)
+	}
+}
+
+func TestDedupSurvivesFailFastOfFirstCaller(t *testing.T) {
+	g := NewGroup()
+	shared := Task{ID: "shared", Fn: func() int { time.Sleep(100 * time.Millisecond); return 42 }}
+	failing := Task{ID: "fail", Fn: func() error { time.Sleep(10 * time.Millisecond); return errors.New("boom") }}
+
+	first := make(chan []TaskResult, 1)
+	go func() {
+		first <- ParallelProcess([]Task{shared, failing}, WithDedup(g), WithFailFast())
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	second := ParallelProcess([]Task{shared}, WithDedup(g))
+	if second[0].Err != nil || second[0].Results[0] != 42 {
+		t.Errorf("joined caller got (%v, %v), want (42, nil)", second[0].Results, second[0].Err)
+	}
+	if r := <-first; !errors.Is(r[0].Err, ErrAborted) {
+		t.Errorf("first caller's shared task Err = %v, want ErrAborted", r[0].Err)
+	}
+}
+
+func TestDedupSkipsEmptyID(t *testing.T) {
+	var runs int32
+	task := Task{Fn: func() { time.Sleep(20 * time.Millisecond); atomic.AddInt32(&runs, 1) }}
+	ParallelProcess([]Task{task, task, task}, WithDedup(NewGroup()))
+	if n := atomic.LoadInt32(&runs); n != 3 {
+		t.Errorf("tasks without ID ran %d times, want 3", n)
+	}
+
+	atomic.StoreInt32(&runs, 0)
+	named := task
+	named.ID = "same"
+	ParallelProcess([]Task{named, named, named}, WithDedup(NewGroup()))
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("tasks with the same ID ran %d times, want 1", n)
+	}
+}
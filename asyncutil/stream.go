@@ -0,0 +1,91 @@
+package asyncutil
+
+import (
+	"context"
+	"sync"
+)
+
+// StreamResult 是 ParallelProcessStream 輸出的結果
+type StreamResult struct {
+	Index int // 任務在 tasks 中的索引，可用於對應結果與任務，不受 ID 是否唯一影響
+	TaskResult
+}
+
+// ParallelProcessStream 平行執行所有任務，並在每個任務完成時立即從返回的通道輸出其結果，
+// 所有任務完成後關閉通道。預設依完成順序輸出，使用 WithOrdered 可改為依提交順序輸出。
+// 呼叫方應持續讀取通道直到關閉；ctx 取消後，尚未送出的結果會被捨棄，通道隨即關閉。
+func ParallelProcessStream(ctx context.Context, tasks []Task, opts ...Option) <-chan StreamResult {
+	o := buildOptions(opts)
+	out := make(chan StreamResult)
+
+	send := func(result StreamResult) {
+		select {
+		case out <- result:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(out)
+
+		if o.orderedWindow <= 0 {
+			processTasks(ctx, tasks, o, nil, func(i int, result TaskResult) {
+				send(StreamResult{Index: i, TaskResult: result})
+			})
+			return
+		}
+
+		buf := newReorderBuffer(o.orderedWindow, send)
+		processTasks(ctx, tasks, o, buf.waitTurn, buf.push)
+	}()
+
+	return out
+}
+
+// reorderBuffer 暫存提前完成的結果，並依提交順序輸出，最多只允許 window 個任務尚未輸出
+type reorderBuffer struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	next    int                // 下一個要輸出的任務索引
+	window  int                // 尚未輸出的任務數量上限
+	pending map[int]TaskResult // 已完成但尚未輪到輸出的結果
+	send    func(StreamResult)
+}
+
+// newReorderBuffer 創建一個新的 reorderBuffer
+func newReorderBuffer(window int, send func(StreamResult)) *reorderBuffer {
+	b := &reorderBuffer{
+		window:  window,
+		pending: make(map[int]TaskResult, window),
+		send:    send,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// waitTurn 阻塞直到任務 i 落在輸出視窗內
+func (b *reorderBuffer) waitTurn(i int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i >= b.next+b.window {
+		b.cond.Wait()
+	}
+}
+
+// push 保存任務 i 的結果，並依序輸出所有已輪到的結果
+func (b *reorderBuffer) push(i int, result TaskResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending[i] = result
+	for {
+		r, ok := b.pending[b.next]
+		if !ok {
+			break
+		}
+		delete(b.pending, b.next)
+		b.send(StreamResult{Index: b.next, TaskResult: r})
+		b.next++
+	}
+	b.cond.Broadcast()
+}
@@ -0,0 +1,41 @@
+package asyncutil
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParallelProcessStreamIndex(t *testing.T) {
+	// 所有任務的 ID 都相同，只能以 Index 對應結果與任務
+	tasks := make([]Task, 20)
+	for i := range tasks {
+		n := i
+		tasks[i] = Task{ID: "same", Fn: func() int { return n * n }}
+	}
+
+	for _, opts := range [][]Option{nil, {WithOrdered(4)}} {
+		seen := make(map[int]bool)
+		next := 0
+		for r := range ParallelProcessStream(context.Background(), tasks, opts...) {
+			if r.Err != nil {
+				t.Fatalf("task %d: %v", r.Index, r.Err)
+			}
+			if got := r.Results[0].(int); got != r.Index*r.Index {
+				t.Errorf("result for index %d = %d, want %d", r.Index, got, r.Index*r.Index)
+			}
+			if seen[r.Index] {
+				t.Errorf("index %d emitted twice", r.Index)
+			}
+			seen[r.Index] = true
+			if opts != nil {
+				if r.Index != next {
+					t.Errorf("ordered stream emitted index %d, want %d", r.Index, next)
+				}
+				next++
+			}
+		}
+		if len(seen) != len(tasks) {
+			t.Errorf("got %d results, want %d", len(seen), len(tasks))
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package jsonutil
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -62,3 +66,42 @@ func LoadJSONFileToStruct(filePath string, result interface{}) error {
 
 	return nil
 }
+
+// AppendJSONLine 將 v 編碼為單行 JSON 並加上換行符號，以一次寫入的方式寫到 w，適合用於 append-only 的 JSON Lines 文件
+func AppendJSONLine(w io.Writer, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// ReadJSONLines 逐行讀取 JSON Lines 格式的資料，並以每一行的原始 JSON 呼叫 fn，空行會被略過。
+// 最後一行若沒有換行符號，視為寫入中斷的不完整資料而略過。
+// 返回最後一個完整行結尾的位元組位置，可用於截斷不完整的尾端；fn 返回錯誤或某一行不是有效的 JSON 時停止讀取並返回該錯誤。
+func ReadJSONLines(r io.Reader, fn func(line json.RawMessage) error) (int64, error) {
+	reader := bufio.NewReader(r)
+	var offset int64
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+
+		content := bytes.TrimSpace(line)
+		if len(content) > 0 {
+			if !json.Valid(content) {
+				return offset, fmt.Errorf("invalid JSON on line %d", lineNumber)
+			}
+			if err := fn(json.RawMessage(content)); err != nil {
+				return offset, err
+			}
+		}
+		offset += int64(len(line))
+	}
+}